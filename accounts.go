@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// account is an AWS account as resolved via the credentials of a profile.
+type account struct {
+	id    string
+	alias string
+	// callerARN is the ARN of the identity (e.g., an assumed role) whose credentials the profile uses
+	callerARN string
+}
+
+// accountPolicy restricts the accounts in which resources can be deleted.
+type accountPolicy struct {
+	// allow, if not empty, are the IDs of the only accounts in which resources can be deleted
+	allow []string
+	// deny are the IDs of accounts in which resources can never be deleted
+	deny []string
+}
+
+// newAccountPolicy returns the policy of the accounts allowed and denied in the config file and via flags.
+// Denied accounts are combined. If accounts are allowed in both, only the accounts allowed in both are allowed,
+// so that the flag can only narrow down the accounts allowed by the config file.
+func newAccountPolicy(cfgAllow, flagAllow, cfgDeny, flagDeny []string) (accountPolicy, error) {
+	result := accountPolicy{
+		allow: append(cfgAllow, flagAllow...),
+		deny:  append(cfgDeny, flagDeny...),
+	}
+
+	if len(cfgAllow) == 0 || len(flagAllow) == 0 {
+		return result, nil
+	}
+
+	result.allow = nil
+
+	for _, id := range flagAllow {
+		for _, cfgID := range cfgAllow {
+			if id == cfgID {
+				result.allow = append(result.allow, id)
+				break
+			}
+		}
+	}
+
+	if len(result.allow) == 0 {
+		return accountPolicy{}, fmt.Errorf("none of the accounts of --allow-account is allowed by " +
+			"allow_accounts in the config file")
+	}
+
+	return result, nil
+}
+
+func (p accountPolicy) isEmpty() bool {
+	return len(p.allow) == 0 && len(p.deny) == 0
+}
+
+// check returns an error if resources can't be deleted in the given account.
+func (p accountPolicy) check(profile string, a account) error {
+	for _, id := range p.deny {
+		if id == a.id {
+			return fmt.Errorf("account %s of profile %s is denied (--deny-account)", formatAccount(a), profile)
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return nil
+	}
+
+	for _, id := range p.allow {
+		if id == a.id {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("account %s of profile %s is not allowed (--allow-account)", formatAccount(a), profile)
+}
+
+func formatAccount(a account) string {
+	if a.alias == "" {
+		return a.id
+	}
+	return fmt.Sprintf("%s (%s)", a.id, a.alias)
+}
+
+// resolveAccounts sets the ID of the account each resource belongs to, as resolved via the credentials of its profile,
+// and returns the accounts by profile, or an error if the policy doesn't allow to delete resources in any of them.
+// An error is also returned if the account of a resource is known from the input (e.g., from an ARN),
+// but differs from the account of its profile.
+//
+// If resolving an account fails, an error is only returned if the policy is not empty; otherwise, no accounts
+// are returned.
+func resolveAccounts(ctx context.Context, resources []terraform.Resource,
+	policy accountPolicy) (map[string]account, error) {
+	var clientKeys []aws.ClientKey
+	for _, r := range resources {
+		clientKeys = append(clientKeys, aws.ClientKey{Profile: r.Profile, Region: r.Region})
+	}
+
+	accounts, err := accountsByProfile(ctx, clientKeys)
+	if err != nil {
+		if policy.isEmpty() {
+			log.WithError(err).Warn("failed to resolve account IDs of profiles")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var profiles []string
+	for profile := range accounts {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+
+	for _, profile := range profiles {
+		err := policy.check(profile, accounts[profile])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mismatches []string
+
+	for i, r := range resources {
+		a := accounts[r.Profile]
+
+		if r.AccountID == "" {
+			resources[i].AccountID = a.id
+			continue
+		}
+
+		if r.AccountID != a.id {
+			mismatches = append(mismatches, fmt.Sprintf("%s %s belongs to account %s, but profile %s is for account %s",
+				r.Type, r.ID, r.AccountID, r.Profile, formatAccount(a)))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("resources don't belong to the account of their profile: %s",
+			strings.Join(mismatches, "; "))
+	}
+
+	return accounts, nil
+}
+
+// accountsByProfile resolves the account of each profile of the given client keys via GetCallerIdentity.
+// The alias of an account is looked up, too, but is empty if it can't be retrieved (e.g., due to missing permissions).
+func accountsByProfile(ctx context.Context, clientKeys []aws.ClientKey) (map[string]account, error) {
+	// the account only depends on the credentials of a profile, not the region
+	regionByProfile := map[string]string{}
+	for _, k := range clientKeys {
+		regionByProfile[k.Profile] = k.Region
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+
+	result := map[string]account{}
+
+	for profile, region := range regionByProfile {
+		wg.Add(1)
+
+		go func(profile, region string) {
+			defer wg.Done()
+
+			a, err := resolveAccount(ctx, profile, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("profile %s: %s", profile, err))
+				return
+			}
+
+			result[profile] = a
+		}(profile, region)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("failed to resolve account IDs: %s", strings.Join(errs, "; "))
+	}
+
+	return result, nil
+}
+
+func resolveAccount(ctx context.Context, profile, region string) (account, error) {
+	client, err := newClient(ctx, profile, region)
+	if err != nil {
+		return account{}, err
+	}
+
+	identity, err := client.Stsconn.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return account{}, fmt.Errorf("failed to get caller identity: %s", err)
+	}
+
+	a := account{id: *identity.Account, callerARN: *identity.Arn}
+
+	resp, err := client.Iamconn.ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil {
+		log.WithError(err).WithField("profile", profile).Debug("failed to get account alias")
+	} else if len(resp.AccountAliases) > 0 {
+		a.alias = resp.AccountAliases[0]
+	}
+
+	resource.SetAccountAlias(a.id, a.alias)
+
+	log.WithFields(log.Fields{
+		"profile": profile,
+		"account": formatAccount(a),
+	}).Debug("resolved account")
+
+	return a, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/terraform"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// auditLogPath returns the path of the audit log given via flag or in the config file,
+// or the default path ~/.awsrm/audit.log.
+func auditLogPath(flagValue string, cfg config) (string, error) {
+	path := flagValue
+	if path == "" {
+		path = cfg.AuditLog
+	}
+
+	if path == "" {
+		dir, err := configDir()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(dir, "audit.log"), nil
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(home, path[2:]), nil
+	}
+
+	return path, nil
+}
+
+// openAuditLog opens the audit log, creating its directory if needed.
+func openAuditLog(path string) (*internal.AuditLog, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.OpenAuditLog(path)
+}
+
+// newAuditRecord returns the audit record of deleting the requested resources with the given result,
+// using the credentials of the given accounts. The finish time is zero if deleting has only started.
+func newAuditRecord(status string, started, finished time.Time, accounts map[string]account,
+	requested []terraform.Resource, result resource.DeleteResult) internal.AuditRecord {
+	record := internal.AuditRecord{
+		Status:      status,
+		Started:     started.UTC(),
+		User:        localUser(),
+		Callers:     []internal.AuditCaller{},
+		CommandLine: os.Args,
+		Requested:   []internal.AuditResource{},
+		Plan:        []internal.AuditResource{},
+		Outcomes:    []internal.AuditOutcome{},
+	}
+
+	if !finished.IsZero() {
+		finishedUTC := finished.UTC()
+		record.Finished = &finishedUTC
+	}
+
+	for _, r := range requested {
+		record.Requested = append(record.Requested, auditResource(r))
+	}
+
+	profiles := map[string]bool{}
+
+	for _, r := range result.Confirmed {
+		record.Plan = append(record.Plan, auditResource(r))
+		profiles[r.Profile] = true
+	}
+
+	var sortedProfiles []string
+	for profile := range profiles {
+		sortedProfiles = append(sortedProfiles, profile)
+	}
+	sort.Strings(sortedProfiles)
+
+	for _, profile := range sortedProfiles {
+		// the account is unknown if it couldn't be resolved
+		a := accounts[profile]
+
+		record.Callers = append(record.Callers, internal.AuditCaller{
+			Profile: profile,
+			Account: a.id,
+			ARN:     a.callerARN,
+		})
+	}
+
+	for _, r := range result.Deleted {
+		record.Outcomes = append(record.Outcomes, internal.AuditOutcome{
+			AuditResource: auditResource(r),
+			Outcome:       internal.AuditDeleted,
+			State:         stateJSON(r),
+		})
+	}
+
+	for _, f := range result.Failed {
+		record.Outcomes = append(record.Outcomes, internal.AuditOutcome{
+			AuditResource: auditResource(f.Resource),
+			Outcome:       internal.AuditFailed,
+			Error:         f.Err.Error(),
+			State:         stateJSON(f.Resource),
+		})
+	}
+
+	return record
+}
+
+func auditResource(r terraform.Resource) internal.AuditResource {
+	return internal.AuditResource{
+		Type:    r.Type,
+		ID:      r.ID,
+		Profile: r.Profile,
+		Region:  r.Region,
+		Account: r.AccountID,
+	}
+}
+
+// stateJSON returns the state of the resource as JSON, or nil if the state is unknown.
+func stateJSON(r terraform.Resource) []byte {
+	if r.State == nil || r.State.IsNull() {
+		return nil
+	}
+
+	result, err := ctyjson.Marshal(*r.State, r.State.Type())
+	if err != nil {
+		log.WithFields(resource.IdentityFields(r)).WithError(err).Debug("failed to marshal state for audit log")
+		return nil
+	}
+
+	return result
+}
+
+// localUser returns the name of the user running awsrm.
+func localUser() string {
+	u, err := user.Current()
+	if err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"gopkg.in/yaml.v2"
+)
+
+// config are settings read from the config file ~/.awsrm/config.yaml, for example:
+//
+// 	allow_accounts:
+// 	  - "123456789012"
+// 	deny_accounts:
+// 	  - "210987654321"
+// 	audit_log: ~/audit/awsrm.log
+// 	require_reason: true
+// 	tag_reason: true
+type config struct {
+	AllowAccounts []string `yaml:"allow_accounts"`
+	DenyAccounts  []string `yaml:"deny_accounts"`
+	// AuditLog is the path of the audit log (default ~/.awsrm/audit.log)
+	AuditLog string `yaml:"audit_log"`
+	// RequireReason makes --reason mandatory to delete resources (except in a dry run)
+	RequireReason bool `yaml:"require_reason"`
+	// TagReason tags each resource with the reason just before it is deleted, as with --tag-reason,
+	// but resources of supported types that fail to be tagged aren't deleted
+	TagReason bool `yaml:"tag_reason"`
+}
+
+// configDir returns the directory of awsrm's config files (i.e., ~/.awsrm).
+func configDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".awsrm"), nil
+}
+
+// loadConfig reads the config file. If the config file doesn't exist, an empty config is returned.
+func loadConfig() (config, error) {
+	dir, err := configDir()
+	if err != nil {
+		return config{}, err
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config{}, nil
+		}
+		return config{}, err
+	}
+
+	log.WithField("path", path).Debug("reading config file")
+
+	var c config
+
+	err = yaml.UnmarshalStrict(input, &c)
+	if err != nil {
+		return config{}, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// deleteOptions configures which of the given resources are deleted and how.
+type deleteOptions struct {
+	force  bool
+	dryRun bool
+	// selection is how the user selects the resources to delete
+	selection resource.Selection
+	// filter is applied to the fetched state of the resources
+	filter resource.Filter
+	// protection is applied to the fetched state of the resources; protected resources are never deleted
+	protection resource.Protection
+	// ignoreProtectionTags allows to delete resources with a protection tag after an extra confirmation
+	ignoreProtectionTags bool
+	// recursive also deletes all resources that depend on the given ones
+	recursive bool
+	// maxRetries is how often resources that failed to be deleted for a retryable reason are retried
+	maxRetries int
+	// showDependents lists other resources that depend on the resources to delete before deletion
+	showDependents bool
+	// reason is why the resources are deleted (e.g., a ticket reference)
+	reason string
+	// tagReason tags each resource with the reason just before it is deleted
+	tagReason bool
+	// requireTag doesn't delete resources that fail to be tagged with the reason (instead of deleting them untagged)
+	requireTag bool
+	// auditLog is the file to which a record of each run that deletes resources is appended
+	auditLog string
+	// failedOut is the file to which resources that couldn't be deleted are written, if set
+	failedOut string
+	// accounts restricts the accounts in which resources can be deleted
+	accounts accountPolicy
+	// displayAttributes are shown with the resources (i.e., the attributes printed by awsls given as input)
+	displayAttributes resource.DisplayAttributes
+}
+
+// deleteResources launches a Terraform AWS Provider for each profile and region of the given resources,
+// fetches the current state of the resources, and deletes the ones matching the filter (unless protected)
+// after confirmation.
+func deleteResources(ctx context.Context, resources []terraform.Resource, confirmDevice io.Reader,
+	opts deleteOptions) int {
+	resources = resource.RemoveDuplicates(resources)
+
+	for _, r := range resources {
+		resource.EmitEvent(resource.EventInputParsed, r)
+	}
+
+	// accounts are checked before any provider is started
+	accounts, err := resolveAccounts(ctx, resources, opts.accounts)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	if opts.recursive {
+		dependents, err := findDependents(ctx, resources)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
+		}
+
+		resources = append(resources, dependents...)
+	}
+
+	var clientKeys []aws.ClientKey
+	for _, r := range resources {
+		clientKeys = append(clientKeys, aws.ClientKey{Profile: r.Profile, Region: r.Region})
+	}
+
+	providers, err := terraform.NewProviderPool(ctx, clientKeys, terraformAwsProviderVersion, "~/.awsrm", 1*time.Minute)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return exitInterrupted
+		}
+
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+	defer func() {
+		for _, p := range providers {
+			_ = p.Close()
+		}
+	}()
+
+	var numStateErrors int
+
+	resourcesCh := make(chan resource.UpdatedResources, 1)
+	go func() { resourcesCh <- resource.Update(resources, providers) }()
+	select {
+	case <-ctx.Done():
+		return exitInterrupted
+	case result := <-resourcesCh:
+		resources = result.Resources
+		numStateErrors = len(result.Errors)
+
+		for _, err := range result.Errors {
+			fmt.Fprint(os.Stderr, color.RedString("Error: %s\n", err))
+		}
+	}
+
+	resources = resource.ApplyFilter(resources, opts.filter)
+
+	resources, protected := resource.ApplyProtection(resources, opts.protection)
+
+	resources, withProtectionTag := resource.ApplyProtectionTags(resources, opts.protection)
+
+	if len(withProtectionTag) > 0 && opts.ignoreProtectionTags {
+		confirmedCh := make(chan bool, 1)
+		go func() { confirmedCh <- confirmIgnoringProtectionTags(withProtectionTag, confirmDevice, opts.dryRun) }()
+		select {
+		case <-ctx.Done():
+			return exitInterrupted
+		case confirmed := <-confirmedCh:
+			if confirmed {
+				for _, r := range withProtectionTag {
+					resources = append(resources, r.Resource)
+				}
+				withProtectionTag = nil
+			}
+		}
+	}
+
+	protected = append(protected, withProtectionTag...)
+
+	if opts.showDependents {
+		referrers, err := listReferrers(ctx, resources, providers)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return exitInterrupted
+			}
+
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
+		}
+
+		resource.ShowDependents("other resources depending on the resources to delete", resources, referrers)
+	}
+
+	// the audit log is opened before anything is deleted, so that no deletion goes unrecorded
+	var auditLog *internal.AuditLog
+
+	if !opts.dryRun {
+		auditLog, err = openAuditLog(opts.auditLog)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: failed to open audit log: %s\n", err))
+			return exitError
+		}
+		defer auditLog.Close()
+	}
+
+	deleteOpts := resource.DeleteOptions{
+		Force:             opts.force,
+		DryRun:            opts.dryRun,
+		Selection:         opts.selection,
+		Recursive:         opts.recursive,
+		MaxRetries:        opts.maxRetries,
+		Reason:            opts.reason,
+		DisplayAttributes: opts.displayAttributes,
+	}
+
+	if opts.tagReason {
+		deleteOpts.BeforeDestroy = newReasonTagger(ctx, opts.reason, opts.requireTag).tag
+	}
+
+	started := time.Now()
+
+	// deletion is only started if the confirmed plan has been recorded; once started, an interrupt waits
+	// for the deletions in progress, so that their outcomes are recorded, too
+	var mu sync.Mutex
+	deletionStarted := false
+	interrupted := false
+
+	deleteOpts.Interrupted = ctx.Done()
+
+	if !opts.dryRun {
+		deleteOpts.BeforeDeletion = func(confirmed []terraform.Resource) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if interrupted {
+				return context.Canceled
+			}
+
+			record := newAuditRecord(internal.AuditStarted, started, time.Time{}, accounts, resources,
+				resource.DeleteResult{Confirmed: confirmed})
+			record.Reason = opts.reason
+
+			err := auditLog.Append(record)
+			if err != nil {
+				return fmt.Errorf("failed to write audit log: %s", err)
+			}
+
+			deletionStarted = true
+
+			return nil
+		}
+	}
+
+	type deleteResult struct {
+		result resource.DeleteResult
+		err    error
+	}
+
+	resultCh := make(chan deleteResult, 1)
+	go func() {
+		result, err := resource.Delete(resources, protected, confirmDevice, deleteOpts)
+		resultCh <- deleteResult{result, err}
+	}()
+
+	var r deleteResult
+
+	select {
+	case <-ctx.Done():
+		mu.Lock()
+		interrupted = true
+		wait := deletionStarted
+		mu.Unlock()
+
+		// before deletion has started (e.g., while waiting for confirmation), there is nothing to wait for
+		if !wait {
+			return exitInterrupted
+		}
+
+		internal.LogTitle("interrupted, waiting for deletions in progress to finish")
+
+		r = <-resultCh
+	case r = <-resultCh:
+	}
+
+	if r.err != nil {
+		if errors.Is(r.err, context.Canceled) {
+			return exitInterrupted
+		}
+
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", r.err))
+		return exitError
+	}
+
+	deletionAttempted := !opts.dryRun &&
+		(r.result.Status == resource.DeleteCompleted || r.result.Status == resource.DeleteFailed)
+
+	status := internal.AuditFinished
+	if ctx.Err() != nil {
+		status = internal.AuditInterrupted
+	}
+
+	// failing to record the outcome doesn't change the exit code, which reflects what has been deleted
+	if deletionAttempted {
+		record := newAuditRecord(status, started, time.Now(), accounts, resources, r.result)
+		record.Reason = opts.reason
+
+		err := auditLog.Append(record)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: failed to write audit log: %s\n", err))
+		}
+	}
+
+	// the file is only (over)written if deletion has been attempted, so that it is kept when
+	// retrying the resources of the same file is aborted
+	if opts.failedOut != "" && deletionAttempted {
+		err := writeFailedOut(opts.failedOut, r.result.Failed)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: failed to write resources that couldn't be deleted: %s\n", err))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+
+	return exitCode(r.result, numStateErrors)
+}
+
+// writeFailedOut writes the resources that couldn't be deleted to the given file in the format of input
+// via pipe or file. If all resources have been deleted, the file is empty.
+func writeFailedOut(path string, failed []resource.FailedResource) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = resource.WriteFailed(f, failed)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// confirmIgnoringProtectionTags lists the given resources with a protection tag and asks the user to confirm
+// that they are deleted anyway. This confirmation is required even if deletion is forced.
+func confirmIgnoringProtectionTags(resources []resource.ProtectedResource, confirmDevice io.Reader, dryRun bool) bool {
+	internal.LogTitle("ignoring protection tags of the following resources")
+
+	for _, r := range resources {
+		fields := resource.IdentityFields(r.Resource)
+		fields["reason"] = r.Reason
+
+		log.WithFields(fields).Warn(internal.Pad(r.Type))
+	}
+
+	if dryRun {
+		return true
+	}
+
+	return internal.UserConfirmed(confirmDevice,
+		"Are you sure you want to ignore the protection tags of these resources?")
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// dependentQuery lists the resources of a type that depend on a resource, via an EC2 API filter
+// whose value is the ID of the resource (e.g., all subnets with the filter vpc-id=<id of the VPC>).
+type dependentQuery struct {
+	rType  string
+	filter string
+}
+
+// dependentQueries are the queries to find the dependents of each resource type that can be deleted recursively.
+var dependentQueries = map[string][]dependentQuery{
+	"aws_vpc": {
+		{"aws_instance", "vpc-id"},
+		{"aws_nat_gateway", "vpc-id"},
+		{"aws_vpc_endpoint", "vpc-id"},
+		{"aws_network_interface", "vpc-id"},
+		{"aws_subnet", "vpc-id"},
+		{"aws_route_table", "vpc-id"},
+		{"aws_security_group", "vpc-id"},
+		{"aws_network_acl", "vpc-id"},
+		{"aws_internet_gateway", "attachment.vpc-id"},
+	},
+	"aws_subnet": {
+		{"aws_instance", "subnet-id"},
+		{"aws_nat_gateway", "subnet-id"},
+		{"aws_network_interface", "subnet-id"},
+	},
+	"aws_security_group": {
+		{"aws_instance", "instance.group-id"},
+		{"aws_network_interface", "group-id"},
+	},
+}
+
+// findDependents returns all resources that (transitively) depend on the given resources in the same account
+// and region, for example, the subnets, route tables, and instances of a VPC.
+//
+// Resources that are deleted together with the resource they belong to (e.g., the default security group of a VPC
+// or the network interface of an instance) are not returned.
+func findDependents(ctx context.Context, resources []terraform.Resource) ([]terraform.Resource, error) {
+	clients := map[aws.ClientKey]*aws.Client{}
+
+	seen := map[string]bool{}
+	for _, r := range resources {
+		seen[r.Type+" "+r.ID] = true
+	}
+
+	var result []terraform.Resource
+
+	queue := resources
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+
+		key := aws.ClientKey{Profile: r.Profile, Region: r.Region}
+
+		client, ok := clients[key]
+		if !ok {
+			var err error
+
+			client, err = newClient(ctx, r.Profile, r.Region)
+			if err != nil {
+				return nil, err
+			}
+			clients[key] = client
+		}
+
+		for _, q := range dependentQueries[r.Type] {
+			dependents, err := listDependents(ctx, client.Ec2conn, q, r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list dependents of %s %s: %s", r.Type, r.ID, err)
+			}
+
+			for _, d := range dependents {
+				if seen[d.Type+" "+d.ID] {
+					continue
+				}
+				seen[d.Type+" "+d.ID] = true
+
+				log.WithFields(resource.IdentityFields(d)).Debugf("found dependent of %s %s", r.Type, r.ID)
+
+				result = append(result, d)
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// listDependents lists the resources of the query's type that depend on the given resource.
+func listDependents(ctx context.Context, conn *ec2.Client, q dependentQuery,
+	r terraform.Resource) ([]terraform.Resource, error) {
+	name := q.filter
+	filters := []types.Filter{{Name: &name, Values: []string{r.ID}}}
+
+	var result []terraform.Resource
+
+	add := func(id string, ownerID *string, tags []types.Tag) {
+		// only resources in the same account are deleted (e.g., not the ones of participants of a shared VPC)
+		if ownerID != nil && r.AccountID != "" && *ownerID != r.AccountID {
+			return
+		}
+
+		result = append(result, terraform.Resource{
+			Type:      q.rType,
+			ID:        id,
+			Profile:   r.Profile,
+			Region:    r.Region,
+			AccountID: r.AccountID,
+			Tags:      tagsToMap(tags),
+		})
+	}
+
+	switch q.rType {
+	case "aws_instance":
+		p := ec2.NewDescribeInstancesPaginator(conn, &ec2.DescribeInstancesInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, reservation := range resp.Reservations {
+				for _, i := range reservation.Instances {
+					if i.State != nil && i.State.Name == types.InstanceStateNameTerminated {
+						continue
+					}
+					add(*i.InstanceId, reservation.OwnerId, i.Tags)
+				}
+			}
+		}
+	case "aws_nat_gateway":
+		p := ec2.NewDescribeNatGatewaysPaginator(conn, &ec2.DescribeNatGatewaysInput{Filter: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, n := range resp.NatGateways {
+				if n.State == types.NatGatewayStateDeleted || n.State == types.NatGatewayStateDeleting {
+					continue
+				}
+				add(*n.NatGatewayId, nil, n.Tags)
+			}
+		}
+	case "aws_vpc_endpoint":
+		input := &ec2.DescribeVpcEndpointsInput{Filters: filters}
+		for {
+			resp, err := conn.DescribeVpcEndpoints(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range resp.VpcEndpoints {
+				if e.State == types.StateDeleted || e.State == types.StateDeleting {
+					continue
+				}
+				add(*e.VpcEndpointId, e.OwnerId, e.Tags)
+			}
+
+			if resp.NextToken == nil {
+				break
+			}
+			input.NextToken = resp.NextToken
+		}
+	case "aws_network_interface":
+		p := ec2.NewDescribeNetworkInterfacesPaginator(conn, &ec2.DescribeNetworkInterfacesInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, n := range resp.NetworkInterfaces {
+				// interfaces in use are deleted together with their instance, NAT gateway, endpoint, etc.
+				if n.RequesterManaged || n.Status != types.NetworkInterfaceStatusAvailable {
+					continue
+				}
+				add(*n.NetworkInterfaceId, n.OwnerId, n.TagSet)
+			}
+		}
+	case "aws_subnet":
+		p := ec2.NewDescribeSubnetsPaginator(conn, &ec2.DescribeSubnetsInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, s := range resp.Subnets {
+				add(*s.SubnetId, s.OwnerId, s.Tags)
+			}
+		}
+	case "aws_route_table":
+		p := ec2.NewDescribeRouteTablesPaginator(conn, &ec2.DescribeRouteTablesInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, rt := range resp.RouteTables {
+				// the main route table is deleted together with its VPC
+				if isMainRouteTable(rt) {
+					continue
+				}
+				add(*rt.RouteTableId, rt.OwnerId, rt.Tags)
+			}
+		}
+	case "aws_security_group":
+		p := ec2.NewDescribeSecurityGroupsPaginator(conn, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, sg := range resp.SecurityGroups {
+				// the default security group is deleted together with its VPC
+				if sg.GroupName != nil && *sg.GroupName == "default" {
+					continue
+				}
+				add(*sg.GroupId, sg.OwnerId, sg.Tags)
+			}
+		}
+	case "aws_network_acl":
+		p := ec2.NewDescribeNetworkAclsPaginator(conn, &ec2.DescribeNetworkAclsInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, acl := range resp.NetworkAcls {
+				// the default network ACL is deleted together with its VPC
+				if acl.IsDefault {
+					continue
+				}
+				add(*acl.NetworkAclId, acl.OwnerId, acl.Tags)
+			}
+		}
+	case "aws_internet_gateway":
+		p := ec2.NewDescribeInternetGatewaysPaginator(conn, &ec2.DescribeInternetGatewaysInput{Filters: filters})
+		for p.HasMorePages() {
+			resp, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, igw := range resp.InternetGateways {
+				add(*igw.InternetGatewayId, igw.OwnerId, igw.Tags)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("listing resources of type %s is not supported", q.rType)
+	}
+
+	return result, nil
+}
+
+func isMainRouteTable(rt types.RouteTable) bool {
+	for _, a := range rt.Associations {
+		if a.Main {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tagsToMap(tags []types.Tag) map[string]string {
+	result := map[string]string{}
+
+	for _, t := range tags {
+		if t.Key != nil && t.Value != nil {
+			result[*t.Key] = *t.Value
+		}
+	}
+
+	return result
+}
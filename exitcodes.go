@@ -0,0 +1,44 @@
+package main
+
+import "github.com/jckuester/awsrm/pkg/resource"
+
+// Exit codes of awsrm. Code 2 is left out, as it is used for invalid flags.
+const (
+	// exitSuccess means all resources have been deleted (or would have been, in a dry run).
+	exitSuccess = 0
+	// exitError means awsrm failed before deleting anything (e.g., invalid input or arguments).
+	exitError = 1
+	// exitNothingToDo means no resources were found or selected to delete.
+	exitNothingToDo = 3
+	// exitAborted means the user didn't confirm the deletion.
+	exitAborted = 4
+	// exitDeletionsFailed means some resources couldn't be deleted.
+	exitDeletionsFailed = 5
+	// exitStateErrors means the state of some resources couldn't be fetched, so they haven't been deleted.
+	exitStateErrors = 6
+	// exitHasDependents means the why command found resources that depend on the given one.
+	exitHasDependents = 7
+	// exitInterrupted means awsrm has been interrupted (e.g., via Ctrl+C).
+	exitInterrupted = 130
+)
+
+// exitCode returns the exit code for the result of deleting resources. Resources that couldn't be deleted take
+// precedence over errors fetching the state of resources, which take precedence over the status of the deletion.
+func exitCode(result resource.DeleteResult, numStateErrors int) int {
+	if result.Status == resource.DeleteFailed {
+		return exitDeletionsFailed
+	}
+
+	if numStateErrors > 0 {
+		return exitStateErrors
+	}
+
+	switch result.Status {
+	case resource.DeleteNothingToDo:
+		return exitNothingToDo
+	case resource.DeleteAborted:
+		return exitAborted
+	}
+
+	return exitSuccess
+}
@@ -4,13 +4,19 @@ go 1.16
 
 require (
 	github.com/apex/log v1.9.0
+	github.com/aws/aws-sdk-go-v2/service/ec2 v1.1.1
+	github.com/aws/aws-sdk-go-v2/service/iam v1.1.1
+	github.com/aws/aws-sdk-go-v2/service/sts v1.1.1
 	github.com/fatih/color v1.10.0
 	github.com/gruntwork-io/terratest v0.32.7
-	github.com/jckuester/awsls v0.11.1-0.20220213214131-b8a517a4d77f // indirect
+	github.com/jckuester/awsls v0.11.1-0.20220213214131-b8a517a4d77f
 	github.com/jckuester/awstools-lib v0.0.0-20220213052046-75c6b3af770f
 	github.com/jckuester/terradozer v0.1.4-0.20220213063954-58c5291f86e4
 	github.com/onsi/gomega v1.10.5
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
+	github.com/zclconf/go-cty v1.7.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/net v0.0.0-20210220033124-5f55cee0dc0d
+	gopkg.in/yaml.v2 v2.3.0
 )
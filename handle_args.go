@@ -1,10 +1,9 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/apex/log"
 	"github.com/fatih/color"
@@ -14,87 +13,143 @@ import (
 	"golang.org/x/net/context"
 )
 
-func handleInputFromArgs(ctx context.Context, args []string, profile, region string, force bool, dryRun bool) int {
+// handleInputFromArgs deletes the resources given as arguments in each combination of the given profiles and regions
+// (or in all enabled regions of each profile if allRegions is true). Resources of global types are deleted
+// in one region per profile.
+func handleInputFromArgs(ctx context.Context, args []string, profiles, regions []string, allRegions bool,
+	opts deleteOptions) int {
 	log.Debug("input via args")
 
-	rType := resource.PrefixResourceType(args[0])
-	if !terraform.IsType(rType) {
-		fmt.Fprint(os.Stderr, color.RedString("\nError: no resource type found: %s\n", rType))
-		return 1
+	resourcesFromArgs, arns, errs := resource.ParseArgs(args)
+	if len(errs) > 0 {
+		fmt.Fprintln(os.Stderr)
+		for _, err := range errs {
+			fmt.Fprint(os.Stderr, color.RedString("Error: %s\n", err))
+		}
+		return exitError
+	}
+
+	var resources []terraform.Resource
+
+	if len(arns) > 0 {
+		var err error
+
+		resources, err = resourcesFromARNs(ctx, arns, profiles, regions)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
+		}
 	}
 
-	var profiles []string
-	var regions []string
+	if len(resourcesFromArgs) == 0 {
+		return deleteResources(ctx, resources, os.Stdin, opts)
+	}
 
-	if profile != "" {
-		profiles = []string{profile}
-	} else {
+	if len(profiles) == 0 {
 		env, ok := os.LookupEnv("AWS_PROFILE")
 		if ok {
 			profiles = []string{env}
 		}
 	}
 
-	if region != "" {
-		regions = []string{region}
-	}
-
 	clients, err := aws.NewClientPool(ctx, profiles, regions)
 	if err != nil {
 		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
-		return 1
-	}
-
-	var resources []terraform.Resource
-	for _, client := range clients {
-		for _, id := range args[1:] {
-			resources = append(resources, terraform.Resource{
-				Type:    rType,
-				ID:      id,
-				Profile: client.Profile,
-				Region:  client.Region,
-			})
-		}
+		return exitError
 	}
 
 	var clientKeys []aws.ClientKey
+
+	// global resources (e.g., IAM roles) are the same in each region of a profile, so they are only
+	// deleted in the first given region (or the default region) of each profile
+	globalRegions := map[string]string{}
+
 	for k := range clients {
 		clientKeys = append(clientKeys, k)
+
+		globalRegions[k.Profile] = k.Region
+		if len(regions) > 0 {
+			globalRegions[k.Profile] = regions[0]
+		}
 	}
 
-	providers, err := terraform.NewProviderPool(ctx, clientKeys, terraformAwsProviderVersion, "~/.awsrm", 1*time.Minute)
-	if err != nil {
-		if !errors.Is(err, context.Canceled) {
+	if allRegions {
+		clientKeys, err = expandToAllRegions(ctx, clients)
+		if err != nil {
 			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
 		}
-		return 1
 	}
-	defer func() {
-		for _, p := range providers {
-			_ = p.Close()
+
+	for _, clientKey := range clientKeys {
+		for _, r := range resourcesFromArgs {
+			if resource.IsGlobalType(r.Type) && clientKey.Region != globalRegions[clientKey.Profile] {
+				continue
+			}
+
+			r.Profile = clientKey.Profile
+			r.Region = clientKey.Region
+
+			resources = append(resources, r)
 		}
-	}()
-
-	resourcesCh := make(chan resource.UpdatedResources, 1)
-	go func() { resourcesCh <- resource.Update(resources, providers) }()
-	select {
-	case <-ctx.Done():
-		return 1
-	case result := <-resourcesCh:
-		resources = result.Resources
-
-		for _, err := range result.Errors {
-			fmt.Fprint(os.Stderr, color.RedString("Error %s: %s\n", rType, err))
+	}
+
+	return deleteResources(ctx, resources, os.Stdin, opts)
+}
+
+// resourcesFromARNs returns the resources of the given ARNs. The profile for each resource is looked up
+// by the account ID of the ARN, unless a profile is given. ARNs without account ID require a profile
+// (via --profile or AWS_PROFILE).
+func resourcesFromARNs(ctx context.Context, arns []string, profiles, regions []string) ([]terraform.Resource, error) {
+	profile, err := singleValue("profile", profiles)
+	if err != nil {
+		return nil, fmt.Errorf("%s (resources given as ARNs)", err)
+	}
+
+	region, err := singleValue("region", regions)
+	if err != nil {
+		return nil, fmt.Errorf("%s (resources given as ARNs)", err)
+	}
+
+	var resources []terraform.Resource
+	var withoutAccount []string
+
+	for _, arn := range arns {
+		r, err := resource.FromARN(arn)
+		if err != nil {
+			return nil, err
 		}
+
+		r.Profile = profile
+
+		if r.Profile == "" && r.AccountID == "" {
+			withoutAccount = append(withoutAccount, arn)
+		}
+
+		if r.Region == "" {
+			r.Region = region
+		}
+
+		resources = append(resources, r)
 	}
 
-	doneDelete := make(chan bool, 1)
-	go func() { resource.Delete(resources, os.Stdin, force, dryRun, doneDelete) }()
-	select {
-	case <-ctx.Done():
-		return 0
-	case <-doneDelete:
+	// the profile of ARNs without account ID (e.g., of S3 buckets) can't be looked up,
+	// and the default credentials might belong to another account
+	_, ok := os.LookupEnv("AWS_PROFILE")
+	if len(withoutAccount) > 0 && !ok {
+		return nil, fmt.Errorf("no profile given for ARNs without account ID: %s (give a profile via --profile)",
+			strings.Join(withoutAccount, ", "))
+	}
+
+	err = setProfilesByAccountID(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setDefaultRegions(ctx, resources)
+	if err != nil {
+		return nil, err
 	}
 
-	return 0
+	return resources, nil
 }
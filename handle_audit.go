@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/jckuester/awsrm/internal"
+)
+
+// handleAudit runs a subcommand on the audit log at the given path; currently, only verify is supported,
+// which checks the chain of records and reports any removed or edited records.
+func handleAudit(args []string, path string) int {
+	log.Debug("audit command")
+
+	if len(args) != 1 || args[0] != "verify" {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: usage: awsrm audit verify\n"))
+		return exitError
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: failed to open audit log: %s\n", err))
+		return exitError
+	}
+	defer f.Close()
+
+	numRecords, problems, err := internal.VerifyAuditLog(f)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: failed to read audit log: %s\n", err))
+		return exitError
+	}
+
+	if len(problems) == 0 {
+		internal.LogTitle(fmt.Sprintf("audit log verified: %d records", numRecords))
+		log.WithField("path", path).Info("no records have been removed or edited")
+
+		return exitSuccess
+	}
+
+	internal.LogTitle(fmt.Sprintf("problems found in audit log: %d", len(problems)))
+
+	for _, p := range problems {
+		log.WithField("path", path).Warn(p.String())
+	}
+
+	return exitError
+}
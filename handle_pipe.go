@@ -4,81 +4,129 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"time"
 
 	"github.com/apex/log"
 	"github.com/fatih/color"
+	"github.com/jckuester/awsrm/internal"
 	"github.com/jckuester/awsrm/pkg/resource"
-	"github.com/jckuester/awstools-lib/aws"
 	"github.com/jckuester/awstools-lib/terraform"
 )
 
+// stdinFile is the file name that refers to stdin when given via the --file flag.
+const stdinFile = "-"
+
+// isInputFromPipe returns true if stdin is a pipe.
 func isInputFromPipe() bool {
 	fileInfo, _ := os.Stdin.Stat()
 	return fileInfo.Mode()&os.ModeNamedPipe != 0
 }
 
-func handleInputFromPipe(ctx context.Context, force bool, dryRun bool) int {
-	log.Debug("input via pipe")
+// isInputRedirected returns true if stdin is redirected from a file.
+func isInputRedirected() bool {
+	fileInfo, _ := os.Stdin.Stat()
+	return fileInfo.Mode().IsRegular()
+}
 
-	resources, err := resource.Read(os.Stdin)
-	if err != nil {
-		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
-		return 1
+// handleInputFromPipe reads resources from the given files, or stdin if no files are given.
+// If skipInvalid is true, resources of all valid lines are deleted, even if some lines of input are invalid.
+func handleInputFromPipe(ctx context.Context, files []string, skipInvalid bool, opts deleteOptions) int {
+	log.Debug("input via pipe or file")
+
+	if len(files) == 0 {
+		files = []string{stdinFile}
 	}
 
-	var clientKeys []aws.ClientKey
-	for _, r := range resources {
-		clientKeys = append(clientKeys, aws.ClientKey{Profile: r.Profile, Region: r.Region})
+	var resources []terraform.Resource
+	displayAttrs := resource.DisplayAttributes{}
+	readFromStdin := false
+	numInvalidLines := 0
+
+	for _, file := range files {
+		name := file
+		if file == stdinFile {
+			name = "stdin"
+			readFromStdin = true
+		}
+
+		resourcesFromFile, attrsFromFile, err := readFile(file)
+		if err != nil {
+			var lineErrs resource.LineErrors
+			if !errors.As(err, &lineErrs) {
+				fmt.Fprint(os.Stderr, color.RedString("\nError: %s: %s\n", name, err))
+				return exitError
+			}
+
+			for _, lineErr := range lineErrs {
+				fmt.Fprint(os.Stderr, color.RedString("Error: %s:%d: %s: %s\n",
+					name, lineErr.Line, lineErr.Err, lineErr.Text))
+			}
+
+			numInvalidLines += len(lineErrs)
+		}
+
+		resources = append(resources, resourcesFromFile...)
+
+		for k, attrs := range attrsFromFile {
+			displayAttrs[k] = attrs
+		}
 	}
 
-	err = os.Stdin.Close()
+	if numInvalidLines > 0 {
+		if !skipInvalid {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: found %d invalid line(s) in input "+
+				"(use --skip-invalid to delete the resources of all valid lines)\n", numInvalidLines))
+			return exitError
+		}
+
+		internal.LogTitle(fmt.Sprintf("skipping %d invalid line(s) of input", numInvalidLines))
+	}
+
+	err := setProfilesByAccountID(resources)
 	if err != nil {
 		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
-		return 1
+		return exitError
 	}
 
-	providers, err := terraform.NewProviderPool(ctx, clientKeys, terraformAwsProviderVersion, "~/.awsrm", 1*time.Minute)
+	err = setDefaultRegions(ctx, resources)
 	if err != nil {
-		if !errors.Is(err, context.Canceled) {
-			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
-		}
-		return 1
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
 	}
-	defer func() {
-		for _, p := range providers {
-			_ = p.Close()
+
+	// if stdin is used for input, user confirmation must come from the terminal
+	var confirmDevice io.Reader = os.Stdin
+
+	if readFromStdin {
+		err = os.Stdin.Close()
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
 		}
-	}()
-
-	resourcesCh := make(chan resource.UpdatedResources, 1)
-	go func() { resourcesCh <- resource.Update(resources, providers) }()
-	select {
-	case <-ctx.Done():
-		return 1
-	case result := <-resourcesCh:
-		resources = result.Resources
-
-		for _, err := range result.Errors {
-			fmt.Fprint(os.Stderr, color.RedString("Error %s: %s\n", err))
+
+		confirmDevice, err = os.Open("/dev/tty")
+		if err != nil {
+			log.Fatalf("can't open /dev/tty: %s", err)
 		}
 	}
 
-	confirmDevice, err := os.Open("/dev/tty")
-	if err != nil {
-		log.Fatalf("can't open /dev/tty: %s", err)
+	opts.displayAttributes = displayAttrs
+
+	return deleteResources(ctx, resources, confirmDevice, opts)
+}
+
+// readFile reads resources from the given file, or stdin if the file name is "-".
+func readFile(file string) ([]terraform.Resource, resource.DisplayAttributes, error) {
+	if file == stdinFile {
+		return resource.Read(os.Stdin)
 	}
 
-	doneDelete := make(chan bool, 1)
-	go func() {
-		resource.Delete(resources, confirmDevice, force, dryRun, doneDelete)
-	}()
-	select {
-	case <-ctx.Done():
-		return 0
-	case <-doneDelete:
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer f.Close()
 
-	return 0
+	return resource.Read(f)
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awsrm/pkg/resource"
+)
+
+func handleInputFromState(ctx context.Context, path, profile, region string, opts deleteOptions) int {
+	log.WithField("path", path).Debug("input via Terraform state file")
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+	defer f.Close()
+
+	resources, ignored, err := resource.ReadState(f)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s: %s\n", path, err))
+		return exitError
+	}
+
+	if len(ignored) > 0 {
+		internal.LogTitle(fmt.Sprintf("resources of the state that can't be deleted: %d", len(ignored)))
+	}
+	for _, r := range ignored {
+		log.WithFields(log.Fields{
+			"name":   r.Name,
+			"reason": r.Reason,
+		}).Warn(internal.Pad(r.Type))
+	}
+
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	// the state file doesn't store the profile and region of the AWS provider, therefore the given profile is used,
+	// and the given (or default) region if a region can't be derived from the resource
+	for i := range resources {
+		resources[i].Profile = profile
+
+		if resources[i].Region == "" {
+			resources[i].Region = region
+		}
+	}
+
+	err = setDefaultRegions(ctx, resources)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	return deleteResources(ctx, resources, os.Stdin, opts)
+}
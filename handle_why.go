@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// handleWhy lists the resources in the account and region of the given resource that depend on it
+// (i.e., would break or block its deletion), without deleting anything. The exit code is exitHasDependents
+// if there are any.
+func handleWhy(ctx context.Context, args []string, profiles, regions []string) int {
+	log.Debug("why command")
+
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: usage: awsrm why <resource_type> <id>\n"))
+		return exitError
+	}
+
+	rType := resource.PrefixResourceType(args[0])
+	if !terraform.IsType(rType) {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: no resource type found: %s\n", rType))
+		return exitError
+	}
+
+	if _, ok := referringTypes[rType]; !ok {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: finding dependents of type %s is not supported\n", rType))
+		return exitError
+	}
+
+	profile, err := singleValue("profile", profiles)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s (why command)\n", err))
+		return exitError
+	}
+
+	region, err := singleValue("region", regions)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s (why command)\n", err))
+		return exitError
+	}
+
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	client, err := newClient(ctx, profile, region)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	resources := []terraform.Resource{{
+		Type:    rType,
+		ID:      args[1],
+		Profile: profile,
+		Region:  client.Region,
+	}}
+
+	_, err = resolveAccounts(ctx, resources, accountPolicy{})
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	providers, err := terraform.NewProviderPool(ctx, []aws.ClientKey{{Profile: profile, Region: client.Region}},
+		terraformAwsProviderVersion, "~/.awsrm", 1*time.Minute)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return exitInterrupted
+		}
+
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+	defer func() {
+		for _, p := range providers {
+			_ = p.Close()
+		}
+	}()
+
+	updated := resource.Update(resources, providers)
+	for _, err := range updated.Errors {
+		fmt.Fprint(os.Stderr, color.RedString("Error: %s\n", err))
+	}
+
+	if len(updated.Resources) == 0 {
+		if len(updated.Errors) > 0 {
+			return exitStateErrors
+		}
+		return exitNothingToDo
+	}
+
+	referrers, err := listReferrers(ctx, updated.Resources, providers)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return exitInterrupted
+		}
+
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	numDependents := resource.ShowDependents(fmt.Sprintf("resources depending on %s %s", rType, args[1]),
+		updated.Resources, referrers)
+	if numDependents > 0 {
+		return exitHasDependents
+	}
+
+	return exitSuccess
+}
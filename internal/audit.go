@@ -0,0 +1,383 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// AuditRecord is the record of a run of awsrm that deletes resources, which is written to the audit log
+// as a single line of JSON.
+//
+// Records are chained: each record has a sequence number and the SHA-256 of the line of the previous record,
+// so that removed or edited records can be detected (see VerifyAuditLog).
+type AuditRecord struct {
+	// Seq is the number of the record in the audit log, starting at 1.
+	Seq int `json:"seq"`
+	// PrevHash is the hex-encoded SHA-256 of the line of the previous record (empty for the first record).
+	PrevHash string `json:"prev_hash"`
+	// Status is whether deleting has started, finished, or been interrupted (see AuditStarted).
+	Status string `json:"status"`
+	// Started is when the resources to delete were shown to the user for confirmation.
+	Started time.Time `json:"started"`
+	// Finished is when deleting has finished or been interrupted (nil if deleting has only started).
+	Finished *time.Time `json:"finished,omitempty"`
+	// User is the local user who ran awsrm.
+	User string `json:"user"`
+	// Callers are the AWS identities whose credentials were used to delete resources.
+	Callers     []AuditCaller `json:"callers"`
+	CommandLine []string      `json:"command_line"`
+	// Reason is why the resources were deleted, as given via --reason.
+	Reason string `json:"reason,omitempty"`
+	// Requested are the resources awsrm was asked to delete, after filters and protection rules were applied.
+	Requested []AuditResource `json:"requested"`
+	// Plan are the resources the user confirmed to delete.
+	Plan     []AuditResource `json:"plan"`
+	Outcomes []AuditOutcome  `json:"outcomes"`
+}
+
+// Statuses of a record. Before anything is deleted, a record of the confirmed plan is appended with status
+// AuditStarted, which is followed by a record with the outcomes once deleting has finished or been interrupted.
+const (
+	AuditStarted     = "started"
+	AuditFinished    = "finished"
+	AuditInterrupted = "interrupted"
+)
+
+// AuditCaller is the AWS identity of a profile.
+type AuditCaller struct {
+	Profile string `json:"profile"`
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+}
+
+// AuditResource identifies a resource in the audit log.
+type AuditResource struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Profile string `json:"profile,omitempty"`
+	Region  string `json:"region"`
+	Account string `json:"account,omitempty"`
+}
+
+// Outcomes of deleting a resource.
+const (
+	AuditDeleted = "deleted"
+	AuditFailed  = "failed"
+)
+
+// AuditOutcome is the outcome of deleting a resource of the plan.
+type AuditOutcome struct {
+	AuditResource
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+	// State is the last known state of the resource before it was deleted, as JSON.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// auditLockTimeout is how long to wait for another run of awsrm to finish appending to the audit log.
+var auditLockTimeout = 10 * time.Second
+
+// AuditLog is an audit log to which records are appended.
+type AuditLog struct {
+	path string
+	f    *os.File
+}
+
+// OpenAuditLog opens the audit log at the given path to append records to it.
+// The file is created, only readable by the user, if it doesn't exist.
+//
+// An error is returned if the last record is invalid, as new records can't be chained to it.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &AuditLog{path: path, f: f}
+
+	_, err = l.lastRecord()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Close closes the audit log.
+func (l *AuditLog) Close() error {
+	return l.f.Close()
+}
+
+// Append appends the record as a line of JSON to the audit log, chained to the last record in the log.
+// The sequence number and hash of the previous record of the given record are overwritten.
+func (l *AuditLog) Append(record AuditRecord) error {
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	last, err := l.lastRecord()
+	if err != nil {
+		return err
+	}
+
+	record.Seq = 1
+	record.PrevHash = ""
+
+	if last != nil {
+		record.Seq = last.record.Seq + 1
+		record.PrevHash = hashLine(last.raw)
+	}
+
+	out, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.f.Write(append(out, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return l.f.Sync()
+}
+
+// lastRecord returns the last record of the audit log, or nil if the log is empty.
+func (l *AuditLog) lastRecord() (*auditLine, error) {
+	raw, err := lastLine(l.f)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return nil, nil
+	}
+
+	last := &auditLine{raw: raw}
+
+	err = json.Unmarshal(raw, &last.record)
+	if err != nil {
+		return nil, fmt.Errorf("last record of audit log %s is invalid: %s", l.path, err)
+	}
+
+	return last, nil
+}
+
+// lockFile creates the given lock file, waiting for another process to remove it first if it exists,
+// and returns a function to remove it again.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(auditLockTimeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("audit log is locked by another run of awsrm "+
+				"(remove %s if no other run is in progress)", path)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// lastLine returns the last line of the file without the trailing newline, or nil if the file is empty.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 4096
+
+	var line []byte
+
+	// read backwards from the end of the file until the newline before the last line is found
+	for offset := info.Size(); offset > 0; {
+		size := int64(chunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size)
+
+		_, err := f.ReadAt(chunk, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		line = append(chunk, line...)
+
+		trimmed := bytes.TrimRight(line, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+
+	trimmed := bytes.TrimRight(line, "\n")
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	return trimmed, nil
+}
+
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditProblem is a problem found in the audit log, such as a removed or edited record.
+type AuditProblem struct {
+	// Line is the number of the line in the audit log where the problem was found.
+	Line    int
+	Problem string
+}
+
+func (p AuditProblem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Problem)
+}
+
+// VerifyAuditLog checks the chain of records in the audit log and returns the number of records and any problems:
+// gaps in the sequence numbers (i.e., removed records), records whose hash doesn't match the hash stored
+// in the following record (i.e., edited records), and lines that aren't valid records.
+//
+// An edit of the last record can't be detected, as no record follows it.
+func VerifyAuditLog(r io.Reader) (int, []AuditProblem, error) {
+	reader := bufio.NewReader(r)
+
+	var problems []AuditProblem
+	var prev *auditLine
+
+	numRecords := 0
+	lineNumber := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, nil, err
+		}
+
+		if len(line) > 0 {
+			lineNumber++
+		}
+
+		line = bytes.TrimRight(line, "\n")
+
+		if len(line) > 0 {
+			current := &auditLine{number: lineNumber, raw: line}
+
+			jsonErr := json.Unmarshal(line, &current.record)
+			if jsonErr != nil {
+				current.invalid = true
+
+				problems = append(problems, AuditProblem{
+					Line:    lineNumber,
+					Problem: fmt.Sprintf("not a valid record: %s", jsonErr),
+				})
+			} else {
+				numRecords++
+
+				problems = append(problems, checkChain(current, prev)...)
+			}
+
+			prev = current
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	return numRecords, problems, nil
+}
+
+// auditLine is a line of the audit log.
+type auditLine struct {
+	number int
+	raw    []byte
+	record AuditRecord
+	// invalid is true if the line isn't a valid record
+	invalid bool
+}
+
+// checkChain checks that the record of the given line follows the record of the previous line
+// (which is nil for the first line).
+func checkChain(current, prev *auditLine) []AuditProblem {
+	record := current.record
+
+	if prev == nil {
+		var problems []AuditProblem
+
+		if record.Seq > 1 {
+			problems = append(problems, AuditProblem{
+				Line:    current.number,
+				Problem: fmt.Sprintf("%s missing", formatMissingRecords(1, record.Seq-1)),
+			})
+		} else if record.PrevHash != "" {
+			problems = append(problems, AuditProblem{
+				Line:    current.number,
+				Problem: fmt.Sprintf("record %d has been edited (it is the first record, but has a previous hash)", record.Seq),
+			})
+		}
+
+		return problems
+	}
+
+	// the sequence number and hash of an invalid line are unknown, which has been reported already
+	if prev.invalid {
+		return nil
+	}
+
+	prevSeq := prev.record.Seq
+
+	switch {
+	case record.Seq <= prevSeq:
+		return []AuditProblem{{
+			Line:    current.number,
+			Problem: fmt.Sprintf("record %d is out of order (follows record %d)", record.Seq, prevSeq),
+		}}
+	case record.Seq > prevSeq+1:
+		// the hash can't match either, so it isn't reported separately
+		return []AuditProblem{{
+			Line:    current.number,
+			Problem: fmt.Sprintf("%s missing", formatMissingRecords(prevSeq+1, record.Seq-1)),
+		}}
+	}
+
+	if record.PrevHash != hashLine(prev.raw) {
+		return []AuditProblem{{
+			Line: prev.number,
+			Problem: fmt.Sprintf("record %d has been edited (its hash doesn't match the one stored in record %d)",
+				prevSeq, record.Seq),
+		}}
+	}
+
+	return nil
+}
+
+func formatMissingRecords(from, to int) string {
+	if from == to {
+		return fmt.Sprintf("record %d is", from)
+	}
+
+	return fmt.Sprintf("records %d to %d are", from, to)
+}
@@ -0,0 +1,163 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jckuester/awsrm/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendAuditRecords appends a record for each user to a new audit log, each by a separate run,
+// and returns the lines of the log.
+func appendAuditRecords(t *testing.T, users ...string) []string {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	vpc := internal.AuditResource{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1",
+		Account: "123456789012"}
+
+	for i, user := range users {
+		l, err := internal.OpenAuditLog(path)
+		require.NoError(t, err)
+
+		finished := time.Date(2021, 6, i+1, 12, 0, 5, 0, time.UTC)
+
+		err = l.Append(internal.AuditRecord{
+			Status:      internal.AuditFinished,
+			Started:     time.Date(2021, 6, i+1, 12, 0, 0, 0, time.UTC),
+			Finished:    &finished,
+			User:        user,
+			CommandLine: []string{"awsrm", "-p", "dev", "vpc", "vpc-1"},
+			Requested:   []internal.AuditResource{vpc},
+			Plan:        []internal.AuditResource{vpc},
+			Outcomes: []internal.AuditOutcome{
+				{AuditResource: vpc, Outcome: internal.AuditDeleted, State: json.RawMessage(`{"id":"vpc-1"}`)},
+			},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Close())
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = os.Stat(path + ".lock")
+	assert.True(t, os.IsNotExist(err))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestAuditLog_Append(t *testing.T) {
+	lines := appendAuditRecords(t, "alice", "bob")
+	require.Len(t, lines, 2)
+
+	var first, second internal.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.Equal(t, 1, first.Seq)
+	assert.Equal(t, "", first.PrevHash)
+	assert.Equal(t, "alice", first.User)
+	assert.Equal(t, json.RawMessage(`{"id":"vpc-1"}`), first.Outcomes[0].State)
+
+	assert.Equal(t, 2, second.Seq)
+	assert.Len(t, second.PrevHash, 64)
+	assert.Equal(t, "bob", second.User)
+}
+
+func TestAuditLog_InvalidLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	err := ioutil.WriteFile(path, []byte("{\"seq\":1,\"prev_hash\":\"\"}\nnot json\n"), 0600)
+	require.NoError(t, err)
+
+	_, err = internal.OpenAuditLog(path)
+	assert.Error(t, err)
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	lines := appendAuditRecords(t, "alice", "bob", "carol", "dave", "eve")
+
+	var testCases = []struct {
+		name             string
+		lines            []string
+		expectedRecords  int
+		expectedProblems []string
+	}{
+		{
+			name:            "valid",
+			lines:           lines,
+			expectedRecords: 5,
+		},
+		{
+			name:            "empty",
+			expectedRecords: 0,
+		},
+		{
+			name:             "removed record",
+			lines:            []string{lines[0], lines[1], lines[3], lines[4]},
+			expectedRecords:  4,
+			expectedProblems: []string{"line 3: record 3 is missing"},
+		},
+		{
+			name:             "removed records at the beginning",
+			lines:            lines[2:],
+			expectedRecords:  3,
+			expectedProblems: []string{"line 1: records 1 to 2 are missing"},
+		},
+		{
+			name:            "edited record",
+			lines:           []string{lines[0], strings.Replace(lines[1], "bob", "mallory", 1), lines[2], lines[3], lines[4]},
+			expectedRecords: 5,
+			expectedProblems: []string{
+				"line 2: record 2 has been edited (its hash doesn't match the one stored in record 3)",
+			},
+		},
+		{
+			name:             "reordered records",
+			lines:            []string{lines[0], lines[2], lines[1], lines[3], lines[4]},
+			expectedRecords:  5,
+			expectedProblems: []string{"line 2: record 2 is missing", "line 3: record 2 is out of order (follows record 3)", "line 4: record 3 is missing"},
+		},
+		{
+			name:            "invalid line",
+			lines:           []string{lines[0], lines[1], "not json", lines[3], lines[4]},
+			expectedRecords: 4,
+			expectedProblems: []string{
+				"line 3: not a valid record: invalid character 'o' in literal null (expecting 'u')",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := strings.Join(tc.lines, "\n")
+			if len(tc.lines) > 0 {
+				input += "\n"
+			}
+
+			numRecords, problems, err := internal.VerifyAuditLog(strings.NewReader(input))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedRecords, numRecords)
+
+			var actualProblems []string
+			for _, p := range problems {
+				actualProblems = append(actualProblems, p.String())
+			}
+
+			assert.Equal(t, tc.expectedProblems, actualProblems)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ChecklistItem is an item of a checklist that belongs to a group (e.g., all resources of a type in a region).
+// Items of the same group must be next to each other.
+type ChecklistItem struct {
+	Group    string
+	Label    string
+	Selected bool
+}
+
+// ErrChecklistAborted is returned if the user quits a checklist without confirming the selection.
+var ErrChecklistAborted = errors.New("selection aborted")
+
+// checklistHelp describes the keys of a checklist.
+const checklistHelp = "↑/↓ or k/j: move, space: toggle (on a group: toggle all items of the group), " +
+	"a: toggle all, enter: confirm, q: quit"
+
+// TerminalChecklist shows a full-screen checklist on the given terminal,
+// where the user can toggle items before confirming the selection.
+func TerminalChecklist(tty *os.File, title string, items []ChecklistItem) ([]ChecklistItem, error) {
+	fd := int(tty.Fd())
+
+	if !terminal.IsTerminal(fd) {
+		return nil, fmt.Errorf("checklist requires a terminal")
+	}
+
+	_, height, err := terminal.GetSize(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = terminal.Restore(fd, state) }()
+
+	// switch to the alternate screen and hide the cursor
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+
+	return Checklist(tty, tty, height, title, items)
+}
+
+// Checklist reads keys from the given reader and renders the checklist to the given writer after each key,
+// until the user confirms the selection (returning the items with updated selection) or quits.
+func Checklist(r io.Reader, w io.Writer, height int, title string, items []ChecklistItem) ([]ChecklistItem, error) {
+	c := newChecklist(title, items, height)
+
+	keys := bufio.NewReader(r)
+
+	for {
+		c.render(w)
+
+		key, err := readKey(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "up", "k":
+			c.move(-1)
+		case "down", "j":
+			c.move(1)
+		case "pgup":
+			c.move(-c.pageSize())
+		case "pgdown":
+			c.move(c.pageSize())
+		case " ", "x":
+			c.toggle()
+		case "a":
+			c.toggleAll()
+		case "enter":
+			return c.items, nil
+		case "q", "esc", "ctrl-c":
+			return nil, ErrChecklistAborted
+		}
+	}
+}
+
+// readKey reads the next key press, where escape sequences of arrow and page keys are read as one key.
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case 3:
+		return "ctrl-c", nil
+	case 0x1b:
+		// a single escape (i.e., no sequence following in the same read) is the escape key
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+
+		seq := []byte{}
+		for r.Buffered() > 0 && len(seq) < 3 {
+			next, _ := r.ReadByte()
+			seq = append(seq, next)
+
+			if next >= 'A' && next <= 'Z' || next == '~' {
+				break
+			}
+		}
+
+		switch string(seq) {
+		case "[A", "OA":
+			return "up", nil
+		case "[B", "OB":
+			return "down", nil
+		case "[5~":
+			return "pgup", nil
+		case "[6~":
+			return "pgdown", nil
+		}
+
+		return "", nil
+	}
+
+	return string(b), nil
+}
+
+type checklistRow struct {
+	group string
+	// item is the index of the item, or -1 for the header row of a group
+	item int
+}
+
+type checklist struct {
+	title  string
+	items  []ChecklistItem
+	rows   []checklistRow
+	height int
+	cursor int
+	offset int
+}
+
+func newChecklist(title string, items []ChecklistItem, height int) *checklist {
+	c := &checklist{title: title, height: height}
+
+	c.items = make([]ChecklistItem, len(items))
+	copy(c.items, items)
+
+	for i, item := range c.items {
+		if i == 0 || item.Group != c.items[i-1].Group {
+			c.rows = append(c.rows, checklistRow{group: item.Group, item: -1})
+		}
+		c.rows = append(c.rows, checklistRow{group: item.Group, item: i})
+	}
+
+	// start on the first item rather than the header of the first group
+	if len(c.rows) > 1 {
+		c.cursor = 1
+	}
+
+	return c
+}
+
+// pageSize is the number of rows that fit on the screen below title, help, and selection count.
+func (c *checklist) pageSize() int {
+	if c.height-5 < 1 {
+		return 1
+	}
+	return c.height - 5
+}
+
+func (c *checklist) move(n int) {
+	c.cursor += n
+
+	if c.cursor < 0 {
+		c.cursor = 0
+	}
+	if c.cursor > len(c.rows)-1 {
+		c.cursor = len(c.rows) - 1
+	}
+
+	if c.cursor < c.offset {
+		c.offset = c.cursor
+	}
+	if c.cursor >= c.offset+c.pageSize() {
+		c.offset = c.cursor - c.pageSize() + 1
+	}
+}
+
+func (c *checklist) toggle() {
+	if len(c.rows) == 0 {
+		return
+	}
+
+	row := c.rows[c.cursor]
+
+	if row.item >= 0 {
+		c.items[row.item].Selected = !c.items[row.item].Selected
+		return
+	}
+
+	selectAll := c.groupState(row.group) != "x"
+	for i := range c.items {
+		if c.items[i].Group == row.group {
+			c.items[i].Selected = selectAll
+		}
+	}
+}
+
+func (c *checklist) toggleAll() {
+	selectAll := false
+	for _, item := range c.items {
+		if !item.Selected {
+			selectAll = true
+			break
+		}
+	}
+
+	for i := range c.items {
+		c.items[i].Selected = selectAll
+	}
+}
+
+// groupState returns "x" if all items of a group are selected, "-" if some, and " " if none.
+func (c *checklist) groupState(group string) string {
+	numItems, numSelected := 0, 0
+
+	for _, item := range c.items {
+		if item.Group != group {
+			continue
+		}
+
+		numItems++
+		if item.Selected {
+			numSelected++
+		}
+	}
+
+	switch numSelected {
+	case 0:
+		return " "
+	case numItems:
+		return "x"
+	default:
+		return "-"
+	}
+}
+
+func (c *checklist) render(w io.Writer) {
+	var b strings.Builder
+
+	numSelected := 0
+	for _, item := range c.items {
+		if item.Selected {
+			numSelected++
+		}
+	}
+
+	// move the cursor to the top left corner and clear the screen
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "\x1b[1m%s\x1b[0m\r\n", c.title)
+	fmt.Fprintf(&b, "%s\r\n", checklistHelp)
+	fmt.Fprintf(&b, "selected: %d of %d\r\n\r\n", numSelected, len(c.items))
+
+	end := c.offset + c.pageSize()
+	if end > len(c.rows) {
+		end = len(c.rows)
+	}
+
+	for i := c.offset; i < end; i++ {
+		row := c.rows[i]
+
+		var line string
+		if row.item < 0 {
+			line = fmt.Sprintf("[%s] \x1b[1m%s\x1b[22m", c.groupState(row.group), row.group)
+		} else {
+			item := c.items[row.item]
+
+			state := " "
+			if item.Selected {
+				state = "x"
+			}
+
+			line = fmt.Sprintf("    [%s] %s", state, item.Label)
+		}
+
+		if i == c.cursor {
+			line = "\x1b[7m" + line + "\x1b[27m"
+		}
+
+		b.WriteString(line + "\r\n")
+	}
+
+	fmt.Fprint(w, b.String())
+}
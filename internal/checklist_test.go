@@ -0,0 +1,77 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awsrm/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecklist(t *testing.T) {
+	items := []internal.ChecklistItem{
+		{Group: "dev / us-east-1 / aws_instance", Label: "i-1", Selected: true},
+		{Group: "dev / us-east-1 / aws_instance", Label: "i-2", Selected: true},
+		{Group: "dev / us-east-1 / aws_vpc", Label: "vpc-1", Selected: true},
+		{Group: "dev / us-east-1 / aws_vpc", Label: "vpc-2", Selected: true},
+	}
+
+	tests := []struct {
+		name             string
+		keys             string
+		expectedSelected []string
+		expectedErr      error
+	}{
+		{
+			name:             "confirm without changes",
+			keys:             "\r",
+			expectedSelected: []string{"i-1", "i-2", "vpc-1", "vpc-2"},
+		},
+		{
+			name:             "toggle item",
+			keys:             "j \r",
+			expectedSelected: []string{"i-1", "vpc-1", "vpc-2"},
+		},
+		{
+			name:             "toggle item via arrow keys",
+			keys:             "\x1b[B\x1b[B\x1b[B\x1b[B\x1b[A \r",
+			expectedSelected: []string{"i-1", "i-2", "vpc-2"},
+		},
+		{
+			name:             "toggle group",
+			keys:             "jj \r",
+			expectedSelected: []string{"i-1", "i-2"},
+		},
+		{
+			name:             "toggle all",
+			keys:             "a \r",
+			expectedSelected: []string{"i-1"},
+		},
+		{
+			name:        "quit",
+			keys:        " q",
+			expectedErr: internal.ErrChecklistAborted,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := internal.Checklist(strings.NewReader(tc.keys), ioutil.Discard, 20, "title", items)
+			if tc.expectedErr != nil {
+				require.Equal(t, tc.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var actualSelected []string
+			for _, item := range actual {
+				if item.Selected {
+					actualSelected = append(actualSelected, item.Label)
+				}
+			}
+
+			assert.Equal(t, tc.expectedSelected, actualSelected)
+		})
+	}
+}
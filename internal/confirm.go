@@ -12,8 +12,13 @@ import (
 
 // UserConfirmedDeletion asks the user to confirm before destroying any resources.
 func UserConfirmedDeletion(r io.Reader) bool {
-	log.Info("Are you sure you want to delete these resources (cannot be undone)? Only YES will be accepted.")
-	fmt.Print(fmt.Sprintf("%23v", "Enter a value: "))
+	return UserConfirmed(r, "Are you sure you want to delete these resources (cannot be undone)?")
+}
+
+// UserConfirmed asks the user the given yes/no question.
+func UserConfirmed(r io.Reader, question string) bool {
+	log.Info(question + " Only YES will be accepted.")
+	fmt.Fprintf(Output, "%23v", "Enter a value: ")
 
 	var response string
 
@@ -29,3 +34,48 @@ func UserConfirmedDeletion(r io.Reader) bool {
 
 	return false
 }
+
+// Answer is the answer of a user when asked whether to delete a single resource.
+type Answer int
+
+const (
+	// AnswerNo skips the resource.
+	AnswerNo Answer = iota
+	// AnswerYes selects the resource for deletion.
+	AnswerYes
+	// AnswerAll selects the resource and all remaining ones for deletion.
+	AnswerAll
+	// AnswerQuit skips the resource and all remaining ones.
+	AnswerQuit
+)
+
+// UserAnswer asks the user whether to delete a single resource until one of the answers
+// y(es), n(o), a(ll), or q(uit) is given. If no more input can be read, AnswerQuit is returned.
+func UserAnswer(r io.Reader) Answer {
+	for {
+		fmt.Fprintf(Output, "%23v", "Delete? [y/n/a/q]: ")
+
+		var response string
+
+		_, err := fmt.Fscanln(r, &response)
+		if err != nil && err.Error() != "unexpected newline" {
+			if err != io.EOF {
+				fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			}
+			return AnswerQuit
+		}
+
+		switch strings.ToLower(response) {
+		case "y", "yes":
+			return AnswerYes
+		case "n", "no":
+			return AnswerNo
+		case "a", "all":
+			return AnswerAll
+		case "q", "quit":
+			return AnswerQuit
+		}
+
+		log.Info("Please answer y (yes), n (no), a (all remaining), or q (quit and skip all remaining).")
+	}
+}
@@ -37,3 +37,24 @@ func TestUserConfirmedDeletion(t *testing.T) {
 		})
 	}
 }
+
+func TestUserAnswer(t *testing.T) {
+	tests := []struct {
+		name           string
+		userInput      string
+		expectedAnswer internal.Answer
+	}{
+		{name: "yes", userInput: "y\n", expectedAnswer: internal.AnswerYes},
+		{name: "no", userInput: "no\n", expectedAnswer: internal.AnswerNo},
+		{name: "all", userInput: "A\n", expectedAnswer: internal.AnswerAll},
+		{name: "quit", userInput: "q\n", expectedAnswer: internal.AnswerQuit},
+		{name: "asks again on invalid answer", userInput: "foo\n\ny\n", expectedAnswer: internal.AnswerYes},
+		{name: "quit on end of input", userInput: "", expectedAnswer: internal.AnswerQuit},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualAnswer := internal.UserAnswer(strings.NewReader(tc.userInput))
+			assert.Equal(t, tc.expectedAnswer, actualAnswer)
+		})
+	}
+}
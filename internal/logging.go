@@ -2,6 +2,8 @@ package internal
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/apex/log"
@@ -15,6 +17,10 @@ const DefaultInitialPadding = 3
 // ExtraPadding is the double of the DefaultInitialPadding.
 const ExtraPadding = DefaultInitialPadding * 2
 
+// Output is where prompts and other text for the user (besides log lines) are written. It is set to stderr
+// if machine-readable output is written to stdout.
+var Output io.Writer = os.Stdout
+
 // LogTitle pretty prints a given title.
 func LogTitle(title string) {
 	cli.Default.Padding = DefaultInitialPadding
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AWSConfigFile returns the path to the shared AWS config file,
+// which is either set via the environment variable AWS_CONFIG_FILE or defaults to ~/.aws/config.
+func AWSConfigFile() (string, error) {
+	path, ok := os.LookupEnv("AWS_CONFIG_FILE")
+	if ok {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".aws", "config"), nil
+}
+
+// ProfilesByAccountID returns the names of the profiles in the given AWS config file keyed by account ID.
+//
+// The account ID of a profile is only known if the profile has the setting sso_account_id or role_arn.
+// If multiple profiles belong to the same account, the first one in the config file is returned.
+func ProfilesByAccountID(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := map[string]string{}
+
+	var profile string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(strings.Trim(line, "[]"))
+
+			switch {
+			case section == "default":
+				profile = section
+			case strings.HasPrefix(section, "profile "):
+				profile = strings.TrimSpace(strings.TrimPrefix(section, "profile "))
+			default:
+				// e.g., [sso-session ...] sections
+				profile = ""
+			}
+
+			continue
+		}
+
+		if profile == "" {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(kv[0])
+		value := strings.TrimSpace(kv[1])
+
+		var accountID string
+
+		switch key {
+		case "sso_account_id":
+			accountID = value
+		case "role_arn":
+			// arn:aws:iam::<account_id>:role/<name>
+			parts := strings.Split(value, ":")
+			if len(parts) > 4 {
+				accountID = parts[4]
+			}
+		}
+
+		if accountID == "" {
+			continue
+		}
+
+		if _, ok := result[accountID]; !ok {
+			result[accountID] = profile
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jckuester/awsrm/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAWSConfig = `
+[default]
+region = us-east-1
+
+[profile dev]
+sso_account_id = 111111111111
+sso_role_name = Admin
+region = us-west-2
+
+# assumed role in the production account
+[profile prod]
+role_arn = arn:aws:iam::222222222222:role/admin
+source_profile = default
+
+[profile prod-readonly]
+role_arn = arn:aws:iam::222222222222:role/readonly
+source_profile = default
+
+[sso-session my-sso]
+sso_account_id = 333333333333
+`
+
+func TestProfilesByAccountID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsrm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testAWSConfig), 0600))
+
+	actualProfiles, err := internal.ProfilesByAccountID(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"111111111111": "dev",
+		"222222222222": "prod",
+	}, actualProfiles)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/apex/log/handlers/cli"
 	"github.com/fatih/color"
 	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awsrm/pkg/resource"
 	flag "github.com/spf13/pflag"
 )
 
@@ -25,10 +26,35 @@ func main() {
 func mainExitCode() int {
 	var logDebug bool
 	var version bool
-	var profile string
-	var region string
+	var profiles []string
+	var regions []string
+	var allRegions bool
 	var force bool
 	var dryRun bool
+	var interactive bool
+	var checklist bool
+	var edit bool
+	var fromState string
+	var files []string
+	var skipInvalid bool
+	var tags []string
+	var tagsAbsent []string
+	var conditions []string
+	var olderThan string
+	var newerThan string
+	var includeUnknownAge bool
+	var protectFile string
+	var ignoreProtectionTags bool
+	var allowAccounts []string
+	var denyAccounts []string
+	var recursive bool
+	var maxRetries int
+	var showDependents bool
+	var failedOut string
+	var auditLog string
+	var reason string
+	var tagReason bool
+	var output string
 
 	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
@@ -39,28 +65,99 @@ func mainExitCode() int {
 	flags.BoolVar(&logDebug, "debug", false, "Enable debug logging")
 	flags.BoolVar(&force, "force", false, "Delete without asking for confirmation. Use with caution!")
 	flags.BoolVar(&dryRun, "dry-run", false, "Don't delete anything, just show what would be deleted")
-	flags.StringVarP(&profile, "profile", "p", "", "The AWS profile for the account to delete resources in")
-	flags.StringVarP(&region, "region", "r", "", "The region to delete resources in")
+	flags.BoolVarP(&interactive, "interactive", "i", false,
+		"Ask for each resource whether to delete it (answers: y(es), n(o), a(ll remaining), q(uit))")
+	flags.BoolVar(&checklist, "checklist", false,
+		"Select the resources to delete in a full-screen checklist, grouped by profile, region, and type")
+	flags.BoolVar(&edit, "edit", false,
+		"Edit the list of resources to delete in $EDITOR before confirming (remove or comment out lines to keep resources)")
+	flags.StringSliceVarP(&profiles, "profile", "p", nil,
+		"The AWS profile(s) for the account(s) to delete resources in (comma-separated or repeated)")
+	flags.StringSliceVarP(&regions, "region", "r", nil,
+		"The region(s) to delete resources in (comma-separated or repeated)")
+	flags.BoolVar(&allRegions, "all-regions", false,
+		"Delete resources in all regions enabled for the account of each profile")
+	flags.StringArrayVarP(&files, "file", "f", nil,
+		"Read resources to delete from a file (can be repeated; - reads from stdin)")
+	flags.BoolVar(&skipInvalid, "skip-invalid", false,
+		"Skip invalid lines of input and delete the resources of all valid lines")
+	flags.StringArrayVar(&tags, "tag", nil,
+		"Only delete resources with the given tag of form <key>=<value> (can be repeated)")
+	flags.StringArrayVar(&tagsAbsent, "tag-absent", nil,
+		"Only delete resources without a tag with the given key (can be repeated)")
+	flags.StringArrayVar(&conditions, "where", nil,
+		"Only delete resources whose attribute matches a condition of form '<attribute> <op> <value>',\n"+
+			"where <op> is one of =, !=, =~, !~, <, <=, >, >= (can be repeated)")
+	flags.StringVar(&olderThan, "older-than", "",
+		"Only delete resources created longer ago than the given age (e.g., 72h, 3d, 1w)")
+	flags.StringVar(&newerThan, "newer-than", "",
+		"Only delete resources created less long ago than the given age (e.g., 30m, 12h, 1d)")
+	flags.BoolVar(&includeUnknownAge, "include-unknown-age", false,
+		"Also delete resources without a known creation time if --older-than or --newer-than is given")
+	flags.StringVar(&protectFile, "protect-file", "",
+		"YAML file of resources that must never be deleted (default ~/.awsrm/protect.yaml)")
+	flags.BoolVar(&ignoreProtectionTags, "ignore-protection-tag", false,
+		"Also delete resources with a protection tag (e.g., DoNotDelete) after an extra confirmation")
+	flags.StringSliceVar(&allowAccounts, "allow-account", nil,
+		"Only delete resources in the accounts with the given IDs (comma-separated or repeated)")
+	flags.StringSliceVar(&denyAccounts, "deny-account", nil,
+		"Never delete resources in the accounts with the given IDs (comma-separated or repeated)")
+	flags.BoolVarP(&recursive, "recursive", "R", false,
+		"Also delete all resources that depend on the given ones (e.g., the subnets and instances of a VPC)")
+	flags.IntVar(&maxRetries, "max-retries", 3,
+		"How often to retry deleting resources that failed due to dependencies (e.g., DependencyViolation) or throttling")
+	flags.BoolVar(&showDependents, "show-dependents", false,
+		"Show other resources that depend on the resources to delete (e.g., instances using a security group)")
+	flags.StringVar(&failedOut, "failed-out", "",
+		"Write resources that couldn't be deleted to the given file, which can be used as input via --file to retry")
+	flags.StringVar(&reason, "reason", "",
+		"Why the resources are deleted (e.g., a ticket reference), which is shown and recorded in the audit log")
+	flags.BoolVar(&tagReason, "tag-reason", false,
+		"Tag each resource with the reason (awsrm:deleted-reason=<reason>) just before it is deleted")
+	flags.StringVar(&auditLog, "audit-log", "",
+		"Append a record of each run that deletes resources to the given file (default ~/.awsrm/audit.log)")
+	flags.StringVarP(&output, "output", "o", "text",
+		"Output format: text, json (all events as an array after the run), or ndjson (one event per line)")
+	flags.StringVar(&fromState, "from-state", "", "Delete all AWS resources managed by the given Terraform state file")
 	flags.BoolVar(&version, "version", false, "Show application version")
 
 	_ = flags.Parse(os.Args[1:])
 	args := flags.Args()
 
-	fmt.Println()
-	defer fmt.Println()
-
 	// discard TRACE logs of GRPCProvider
 	stdlog.SetOutput(ioutil.Discard)
 
 	log.SetHandler(cli.Default)
 
+	if output != "text" {
+		format, err := resource.ParseEventFormat(output)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+			return exitError
+		}
+
+		// stdout is reserved for events; log lines already go to stderr
+		internal.Output = os.Stderr
+		resource.SetEventOutput(os.Stdout, format)
+
+		defer func() {
+			err := resource.FlushEvents()
+			if err != nil {
+				fmt.Fprint(os.Stderr, color.RedString("\nError: failed to write events: %s\n", err))
+			}
+		}()
+	}
+
+	fmt.Fprintln(internal.Output)
+	defer fmt.Fprintln(internal.Output)
+
 	if logDebug {
 		log.SetLevel(log.DebugLevel)
 	}
 
 	if version {
 		fmt.Println(internal.BuildVersionString())
-		return 0
+		return exitSuccess
 	}
 
 	ctx := context.Background()
@@ -84,16 +181,185 @@ func mainExitCode() int {
 		}
 	}()
 
-	if isInputFromPipe() {
-		return handleInputFromPipe(ctx, force, dryRun)
+	if fromState == "" && len(args) == 2 && args[0] == "state" {
+		fromState = args[1]
+	}
+
+	filter, err := resource.NewFilter(tags, tagsAbsent, conditions)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	if olderThan != "" {
+		filter.OlderThan, err = resource.ParseAge(olderThan)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: --older-than: %s\n", err))
+			return exitError
+		}
+	}
+
+	if newerThan != "" {
+		filter.NewerThan, err = resource.ParseAge(newerThan)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: --newer-than: %s\n", err))
+			return exitError
+		}
+	}
+
+	filter.IncludeUnknownAge = includeUnknownAge
+
+	protection, err := loadProtection(protectFile)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: failed to read protection file: %s\n", err))
+		return exitError
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: failed to read config file: %s\n", err))
+		return exitError
+	}
+
+	auditLogFile, err := auditLogPath(auditLog, cfg)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: failed to locate audit log: %s\n", err))
+		return exitError
+	}
+
+	selection := resource.SelectAll
+	numSelectionModes := 0
+
+	if interactive {
+		selection = resource.SelectEach
+		numSelectionModes++
+	}
+	if checklist {
+		selection = resource.SelectChecklist
+		numSelectionModes++
+	}
+	if edit {
+		selection = resource.SelectEditor
+		numSelectionModes++
+	}
+
+	if numSelectionModes > 1 {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: only one of --interactive, --checklist, and --edit can be used\n"))
+		return exitError
+	}
+
+	if numSelectionModes > 0 && force {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: --force can't be used together with "+
+			"--interactive, --checklist, or --edit\n"))
+		return exitError
+	}
+
+	if maxRetries < 0 {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: --max-retries must not be negative\n"))
+		return exitError
+	}
+
+	accounts, err := newAccountPolicy(cfg.AllowAccounts, allowAccounts, cfg.DenyAccounts, denyAccounts)
+	if err != nil {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: %s\n", err))
+		return exitError
+	}
+
+	opts := deleteOptions{
+		force:                force,
+		dryRun:               dryRun,
+		selection:            selection,
+		filter:               filter,
+		protection:           protection,
+		ignoreProtectionTags: ignoreProtectionTags,
+		recursive:            recursive,
+		maxRetries:           maxRetries,
+		showDependents:       showDependents,
+		failedOut:            failedOut,
+		auditLog:             auditLogFile,
+		reason:               strings.TrimSpace(reason),
+		tagReason:            tagReason || cfg.TagReason,
+		requireTag:           cfg.TagReason,
+		accounts:             accounts,
+	}
+
+	if allRegions && len(regions) > 0 {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: --all-regions can't be used together with --region\n"))
+		return exitError
+	}
+
+	if len(args) > 0 && args[0] == "why" {
+		return handleWhy(ctx, args[1:], profiles, regions)
+	}
+
+	if len(args) > 0 && args[0] == "audit" {
+		return handleAudit(args[1:], auditLogFile)
+	}
+
+	if opts.reason == "" && cfg.RequireReason && !dryRun {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: a reason is required to delete resources (--reason), "+
+			"as set by require_reason in the config file\n"))
+		return exitError
+	}
+
+	if opts.reason == "" && tagReason {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: --tag-reason requires --reason\n"))
+		return exitError
+	}
+
+	// the tag_reason setting only applies if a reason is given
+	opts.tagReason = opts.tagReason && opts.reason != ""
+
+	// a redirect of stdin is only read as input if no arguments are given,
+	// otherwise stdin is left for the confirmation to delete
+	inputFromStdin := isInputFromPipe() || (len(args) == 0 && isInputRedirected())
+
+	if recursive && (fromState != "" || len(files) > 0 || inputFromStdin) {
+		fmt.Fprint(os.Stderr, color.RedString("\nError: --recursive can only be used with resources given as arguments\n"))
+		return exitError
+	}
+
+	if fromState != "" {
+		if allRegions {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: --all-regions can't be used with a Terraform state file\n"))
+			return exitError
+		}
+
+		profile, err := singleValue("profile", profiles)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s (resources from a Terraform state file)\n", err))
+			return exitError
+		}
+
+		region, err := singleValue("region", regions)
+		if err != nil {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: %s (resources from a Terraform state file)\n", err))
+			return exitError
+		}
+
+		return handleInputFromState(ctx, fromState, profile, region, opts)
+	}
+
+	if len(files) > 0 || inputFromStdin {
+		if len(args) > 0 {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: arguments can't be used together with input via pipe or file\n"))
+			return exitError
+		}
+
+		if allRegions {
+			fmt.Fprint(os.Stderr, color.RedString("\nError: --all-regions can't be used together with input via pipe or file\n"))
+			return exitError
+		}
+
+		return handleInputFromPipe(ctx, files, skipInvalid, opts)
 	}
 
-	if len(args) < 2 {
+	if len(args) == 0 {
 		printHelp(flags)
-		return 1
+		return exitError
 	}
 
-	return handleInputFromArgs(ctx, args, profile, region, force, dryRun)
+	return handleInputFromArgs(ctx, args, profiles, regions, allRegions, opts)
 }
 
 func printHelp(fs *flag.FlagSet) {
@@ -106,16 +372,104 @@ awsrm - A remove command for AWS resources.
 
 USAGE:
   $ awsrm [flags] <resource_type> <id> [<id>...]
+  $ awsrm [flags] <resource_type>:<id> [<resource_type>:<id>...]
+  $ awsrm [flags] <arn> [<arn>...]
+  $ awsrm [flags] state <path/to/terraform.tfstate>
+  $ awsrm [flags] why <resource_type> <id>
+  $ awsrm [--audit-log <file>] audit verify
 
 The resource type and ID(s) are required arguments to delete resource(s).
+Resources of different types can be given as <resource_type>:<id> (e.g., vpc:vpc-1 instance:i-2),
+also mixed with the form above.
 If no profile and/or region for an AWS account is given, credentials are
 used by the usual precedence of the AWS CLI: environment variables, AWS credentials file, etc.
+Multiple profiles and regions can be given (e.g., -p dev,prod -r us-east-1 -r eu-west-1) to delete
+the resources in each combination of them; --all-regions expands to all regions enabled for each profile.
+
+Alternatively, resources can be given as ARNs, from which the resource type, ID, and region are derived.
+If no profile is given, it is looked up in ~/.aws/config by the account ID of the ARN (awsrm aborts
+if none is found, or if an ARN has no account ID, e.g., of an S3 bucket).
 
 Resources in multiple accounts and regions can be filtered and deleted by piping
 the output of awsls through grep to awsrm:
 
   $ awsls [profile/region flags] vpc -a tags | grep Name=foo | awsrm
 
+The same input can be read from files via the --file flag or a redirect of stdin:
+
+  $ awsrm -f leftovers.txt -f more-leftovers.txt
+  $ awsrm < leftovers.txt
+
+All AWS resources managed by a Terraform state file (version 4) can be deleted via the state command
+or the --from-state flag. As a state file doesn't store provider configuration, resources are deleted
+with the given profile, and in the region of their ARN or, if unknown, the given (or default) region.
+
+Resources can be filtered by tags (--tag, --tag-absent) and other attributes (--where). Filters are
+applied to the current state of each resource fetched via the Terraform AWS Provider, for any kind of input:
+
+  $ awsls -p dev instance | awsrm --tag env=test --tag-absent owner --where 'instance_type =~ ^m5'
+
+Resources can also be selected by age (--older-than, --newer-than), which is derived from a creation
+timestamp in the state (e.g., launch_time, create_date). Resources without a known creation time
+are skipped, unless --include-unknown-age is given.
+
+Resources listed in ~/.awsrm/protect.yaml (or the file given via --protect-file) by ID, name, tag,
+or account are never deleted, even with --force. Resources with a protection tag (awsrm:protect=true,
+DoNotDelete, or one listed under protection_tags in the protection file) are skipped as well, unless
+--ignore-protection-tag is given and confirmed separately.
+
+The account of each profile is resolved via STS and shown in log lines. Deletion can be restricted
+to accounts via --allow-account and --deny-account (or allow_accounts and deny_accounts in
+~/.awsrm/config.yaml), which are checked before anything else happens. If accounts are allowed in
+both, --allow-account can only narrow down the allowed accounts of the config file.
+
+Instead of confirming the deletion of all resources at once, resources can be selected one by one
+(-i, --interactive), in a full-screen checklist (--checklist), or by editing the list of resources
+in $EDITOR (--edit).
+
+With --output json or ndjson, structured events of each stage (input_parsed, state_fetched,
+state_fetch_failed, skipped, already_deleted, protected, planned, confirmed, aborted, deleting, retrying,
+deleted, failed) are written to stdout, while log lines go to stderr.
+
+Resources are deleted in the order of their dependencies, derived from reference attributes in their
+state (e.g., vpc_id). With -R (--recursive), all resources depending on the given ones in the same
+account and region are deleted as well (e.g., the subnets, route tables, and instances of a VPC):
+
+  $ awsrm -R vpc vpc-1234
+
+Resources that fail to be deleted because of dependencies (e.g., DependencyViolation) or throttling
+are retried in later passes with exponential backoff, up to --max-retries times.
+Resources that still couldn't be deleted are written to the file given via --failed-out,
+together with the reason as a comment, to retry them later:
+
+  $ awsrm --failed-out failed.txt -f leftovers.txt
+  $ awsrm -f failed.txt
+
+The why command lists the other resources in the account and region that depend on a resource
+(e.g., the instances using a security group or the functions assuming a role), without deleting
+anything. The same is shown for all resources to delete via --show-dependents (e.g., with --dry-run).
+
+A reason for deletion (e.g., a ticket reference) can be given via --reason, which is shown, recorded
+in the audit log, and, with --tag-reason, applied to each resource as the tag awsrm:deleted-reason just
+before it is deleted. Setting require_reason: true in ~/.awsrm/config.yaml makes --reason mandatory
+(except for dry runs).
+
+Each run that deletes resources is recorded in the audit log ~/.awsrm/audit.log (or the file given via
+--audit-log or audit_log in ~/.awsrm/config.yaml) as a line of JSON: the local user, the ARN of each
+AWS identity used, the command line, the confirmed plan, and the outcome and last known state of each resource.
+Each record includes its sequence number and the SHA-256 of the previous record; the audit verify command
+checks this chain and reports removed or edited records.
+
+EXIT CODES:
+  0    all resources deleted (or would have been, in a dry run)
+  1    error before anything was deleted (e.g., invalid input)
+  3    nothing to do (no resources found or selected)
+  4    deletion not confirmed
+  5    some resources couldn't be deleted
+  6    the state of some resources couldn't be fetched
+  7    why found resources that depend on the given one
+  130  interrupted
+
 For supported resource types and a full help text, see the README in the GitHub repository
 https://github.com/jckuester/awsrm and https://github.com/jckuester/awsls.
 
@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// accountAliases are the aliases of account IDs, which are shown next to the account ID of a resource in log lines.
+var accountAliases = struct {
+	sync.RWMutex
+	m map[string]string
+}{m: map[string]string{}}
+
+// SetAccountAlias sets the alias of an account to show in log lines.
+func SetAccountAlias(accountID, alias string) {
+	accountAliases.Lock()
+	defer accountAliases.Unlock()
+
+	accountAliases.m[accountID] = alias
+}
+
+// formatAccount returns the account ID and, if known, the alias of the account (e.g., "123456789012 (prod)").
+func formatAccount(accountID string) string {
+	accountAliases.RLock()
+	defer accountAliases.RUnlock()
+
+	if alias := accountAliases.m[accountID]; alias != "" {
+		return accountID + " (" + alias + ")"
+	}
+
+	return accountID
+}
+
+// IdentityFields returns the fields to log that identify a resource, i.e. its ID, profile, account, and region.
+func IdentityFields(r terraform.Resource) log.Fields {
+	fields := log.Fields{
+		"id":      r.ID,
+		"profile": r.Profile,
+		"region":  r.Region,
+	}
+
+	if r.AccountID != "" {
+		fields["account"] = formatAccount(r.AccountID)
+	}
+
+	return fields
+}
@@ -0,0 +1,56 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityFields(t *testing.T) {
+	SetAccountAlias("123456789012", "prod")
+
+	tests := []struct {
+		name     string
+		r        terraform.Resource
+		expected log.Fields
+	}{
+		{
+			name: "unknown account",
+			r:    terraform.Resource{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1"},
+			expected: log.Fields{
+				"id":      "vpc-1",
+				"profile": "dev",
+				"region":  "us-east-1",
+			},
+		},
+		{
+			name: "account with alias",
+			r: terraform.Resource{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1",
+				AccountID: "123456789012"},
+			expected: log.Fields{
+				"id":      "vpc-1",
+				"profile": "dev",
+				"account": "123456789012 (prod)",
+				"region":  "us-east-1",
+			},
+		},
+		{
+			name: "account without alias",
+			r: terraform.Resource{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1",
+				AccountID: "210987654321"},
+			expected: log.Fields{
+				"id":      "vpc-1",
+				"profile": "dev",
+				"account": "210987654321",
+				"region":  "us-east-1",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IdentityFields(tc.r))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// ParseArgs parses resources given as arguments of the form
+// 	<resource_type> <id> [<id>...]
+// or as tokens of the form <resource_type>:<id>, or a mix of both (e.g., "vpc vpc-1 instance:i-2").
+// ARNs in place of a resource type are returned separately; after a resource type, an ARN is an ID
+// (e.g., "iam_policy arn:aws:iam::123456789012:policy/foo").
+//
+// The profile and region of the returned resources are empty. An error is returned for each invalid argument.
+func ParseArgs(args []string) ([]terraform.Resource, []string, []error) {
+	var result []terraform.Resource
+	var arns []string
+	var errs []error
+
+	var rType string
+	numIDs := 0
+
+	for _, arg := range args {
+		if rType == "" && IsARN(arg) {
+			arns = append(arns, arg)
+			continue
+		}
+
+		if typeAndID := strings.SplitN(arg, ":", 2); len(typeAndID) == 2 {
+			typedType := PrefixResourceType(typeAndID[0])
+
+			if terraform.IsType(typedType) {
+				if typeAndID[1] == "" {
+					errs = append(errs, fmt.Errorf("argument %s: ID is missing", arg))
+					continue
+				}
+
+				result = append(result, terraform.Resource{Type: typedType, ID: typeAndID[1]})
+
+				continue
+			}
+
+			if rType == "" {
+				errs = append(errs, fmt.Errorf("argument %s: no resource type found: %s", arg, typedType))
+				continue
+			}
+		}
+
+		if rType == "" {
+			rType = PrefixResourceType(arg)
+
+			if !terraform.IsType(rType) {
+				errs = append(errs, fmt.Errorf("argument %s: no resource type found: %s", arg, rType))
+			}
+
+			continue
+		}
+
+		numIDs++
+		result = append(result, terraform.Resource{Type: rType, ID: arg})
+	}
+
+	if rType != "" && numIDs == 0 && terraform.IsType(rType) {
+		errs = append(errs, fmt.Errorf("no ID given for resource type: %s", rType))
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	return result, arns, nil
+}
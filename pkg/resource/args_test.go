@@ -0,0 +1,105 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		expectedResources []terraform.Resource
+		expectedARNs      []string
+		expectedErrs      []string
+	}{
+		{
+			name: "type followed by IDs",
+			args: []string{"vpc", "vpc-1", "vpc-2"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1"},
+				{Type: "aws_vpc", ID: "vpc-2"},
+			},
+		},
+		{
+			name: "type:id tokens",
+			args: []string{"vpc:vpc-1", "aws_instance:i-2", "iam_role:ci-runner"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1"},
+				{Type: "aws_instance", ID: "i-2"},
+				{Type: "aws_iam_role", ID: "ci-runner"},
+			},
+		},
+		{
+			name: "mixed",
+			args: []string{"vpc", "vpc-1", "instance:i-2", "vpc-3"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1"},
+				{Type: "aws_instance", ID: "i-2"},
+				{Type: "aws_vpc", ID: "vpc-3"},
+			},
+		},
+		{
+			name: "ID containing colon",
+			args: []string{"sqs_queue", "https://sqs.us-east-1.amazonaws.com/123456789012/foo"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_sqs_queue", ID: "https://sqs.us-east-1.amazonaws.com/123456789012/foo"},
+			},
+		},
+		{
+			name: "ARN as ID",
+			args: []string{"iam_policy", "arn:aws:iam::123456789012:policy/foo"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_iam_policy", ID: "arn:aws:iam::123456789012:policy/foo"},
+			},
+		},
+		{
+			name: "standalone ARNs",
+			args: []string{"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1", "instance:i-2",
+				"arn:aws:s3:::foo", "sns_topic", "arn:aws:sns:us-east-1:123456789012:bar"},
+			expectedResources: []terraform.Resource{
+				{Type: "aws_instance", ID: "i-2"},
+				{Type: "aws_sns_topic", ID: "arn:aws:sns:us-east-1:123456789012:bar"},
+			},
+			expectedARNs: []string{"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1", "arn:aws:s3:::foo"},
+		},
+		{
+			name: "errors per token",
+			args: []string{"foo:bar", "vpc:", "instance:i-1"},
+			expectedErrs: []string{
+				"argument foo:bar: no resource type found: aws_foo",
+				"argument vpc:: ID is missing",
+			},
+		},
+		{
+			name: "invalid type",
+			args: []string{"foo", "bar"},
+			expectedErrs: []string{
+				"argument foo: no resource type found: aws_foo",
+			},
+		},
+		{
+			name: "type without IDs",
+			args: []string{"vpc"},
+			expectedErrs: []string{
+				"no ID given for resource type: aws_vpc",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualResources, actualARNs, errs := ParseArgs(tc.args)
+
+			var actualErrs []string
+			for _, err := range errs {
+				actualErrs = append(actualErrs, err.Error())
+			}
+
+			assert.Equal(t, tc.expectedErrs, actualErrs)
+			assert.Equal(t, tc.expectedResources, actualResources)
+			assert.Equal(t, tc.expectedARNs, actualARNs)
+		})
+	}
+}
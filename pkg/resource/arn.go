@@ -0,0 +1,172 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// arn represents the parts of an Amazon Resource Name (ARN) of the form:
+//
+//	arn:<partition>:<service>:<region>:<account_id>:<resource_type>[/|:]<resource>
+type arn struct {
+	raw          string
+	partition    string
+	service      string
+	region       string
+	accountID    string
+	resourceType string
+	resource     string
+}
+
+// arnMapping maps an ARN to a Terraform resource type and the ID Terraform uses for that type.
+type arnMapping struct {
+	terraformType string
+	id            func(a arn) string
+}
+
+var (
+	// idFromName uses the resource part of an ARN as Terraform ID.
+	idFromName = func(a arn) string { return a.resource }
+	// idFromLastPathElement uses the resource part of an ARN without path (e.g., of IAM roles) as Terraform ID.
+	idFromLastPathElement = func(a arn) string { return a.resource[strings.LastIndex(a.resource, "/")+1:] }
+	// idFromARN uses the full ARN as Terraform ID.
+	idFromARN = func(a arn) string { return a.raw }
+)
+
+// arnMappings are keyed by "<service>:<resource_type>" of an ARN.
+var arnMappings = map[string]arnMapping{
+	"autoscaling:autoScalingGroup": {"aws_autoscaling_group", func(a arn) string {
+		parts := strings.SplitN(a.resource, "autoScalingGroupName/", 2)
+		return parts[len(parts)-1]
+	}},
+	"cloudformation:stack":              {"aws_cloudformation_stack", idFromARN},
+	"cloudwatch:alarm":                  {"aws_cloudwatch_metric_alarm", idFromName},
+	"dynamodb:table":                    {"aws_dynamodb_table", idFromName},
+	"ec2:dhcp-options":                  {"aws_vpc_dhcp_options", idFromName},
+	"ec2:elastic-ip":                    {"aws_eip", idFromName},
+	"ec2:image":                         {"aws_ami", idFromName},
+	"ec2:instance":                      {"aws_instance", idFromName},
+	"ec2:internet-gateway":              {"aws_internet_gateway", idFromName},
+	"ec2:launch-template":               {"aws_launch_template", idFromName},
+	"ec2:natgateway":                    {"aws_nat_gateway", idFromName},
+	"ec2:network-acl":                   {"aws_network_acl", idFromName},
+	"ec2:network-interface":             {"aws_network_interface", idFromName},
+	"ec2:route-table":                   {"aws_route_table", idFromName},
+	"ec2:security-group":                {"aws_security_group", idFromName},
+	"ec2:snapshot":                      {"aws_ebs_snapshot", idFromName},
+	"ec2:subnet":                        {"aws_subnet", idFromName},
+	"ec2:transit-gateway":               {"aws_ec2_transit_gateway", idFromName},
+	"ec2:volume":                        {"aws_ebs_volume", idFromName},
+	"ec2:vpc":                           {"aws_vpc", idFromName},
+	"ec2:vpc-endpoint":                  {"aws_vpc_endpoint", idFromName},
+	"ec2:vpc-peering-connection":        {"aws_vpc_peering_connection", idFromName},
+	"ec2:vpn-gateway":                   {"aws_vpn_gateway", idFromName},
+	"ecr:repository":                    {"aws_ecr_repository", idFromName},
+	"ecs:cluster":                       {"aws_ecs_cluster", idFromARN},
+	"ecs:service":                       {"aws_ecs_service", idFromARN},
+	"eks:cluster":                       {"aws_eks_cluster", idFromName},
+	"elasticache:cluster":               {"aws_elasticache_cluster", idFromName},
+	"elasticfilesystem:file-system":     {"aws_efs_file_system", idFromName},
+	"elasticloadbalancing:loadbalancer": {"aws_lb", idFromARN},
+	"elasticloadbalancing:loadbalancer/classic": {"aws_elb", idFromName},
+	"elasticloadbalancing:targetgroup":          {"aws_lb_target_group", idFromARN},
+	"iam:group":                                 {"aws_iam_group", idFromLastPathElement},
+	"iam:instance-profile":                      {"aws_iam_instance_profile", idFromLastPathElement},
+	"iam:policy":                                {"aws_iam_policy", idFromARN},
+	"iam:role":                                  {"aws_iam_role", idFromLastPathElement},
+	"iam:user":                                  {"aws_iam_user", idFromLastPathElement},
+	"kms:alias":                                 {"aws_kms_alias", func(a arn) string { return "alias/" + a.resource }},
+	"kms:key":                                   {"aws_kms_key", idFromName},
+	"lambda:function": {"aws_lambda_function", func(a arn) string {
+		// strip the version or alias qualifier
+		return strings.SplitN(a.resource, ":", 2)[0]
+	}},
+	"logs:log-group": {"aws_cloudwatch_log_group", func(a arn) string {
+		return strings.TrimSuffix(a.resource, ":*")
+	}},
+	"rds:cluster":           {"aws_rds_cluster", idFromName},
+	"rds:db":                {"aws_db_instance", idFromName},
+	"rds:pg":                {"aws_db_parameter_group", idFromName},
+	"rds:subgrp":            {"aws_db_subnet_group", idFromName},
+	"route53:hostedzone":    {"aws_route53_zone", idFromName},
+	"s3:":                   {"aws_s3_bucket", idFromName},
+	"secretsmanager:secret": {"aws_secretsmanager_secret", idFromARN},
+	"sns:":                  {"aws_sns_topic", idFromARN},
+	"sqs:":                  {"aws_sqs_queue", sqsQueueURL},
+	"states:stateMachine":   {"aws_sfn_state_machine", idFromARN},
+}
+
+// sqsQueueURL returns the URL of an SQS queue, which is the Terraform ID of aws_sqs_queue.
+func sqsQueueURL(a arn) string {
+	domain := "amazonaws.com"
+	if a.partition == "aws-cn" {
+		domain = "amazonaws.com.cn"
+	}
+
+	return fmt.Sprintf("https://sqs.%s.%s/%s/%s", a.region, domain, a.accountID, a.resource)
+}
+
+// IsARN returns true if the given string looks like an Amazon Resource Name (ARN).
+func IsARN(s string) bool {
+	return strings.HasPrefix(s, "arn:")
+}
+
+// FromARN returns a resource with Terraform type, ID, region, and account ID derived from the given ARN.
+//
+// Note: the profile of the returned resource is empty, as an ARN only contains the account ID.
+func FromARN(s string) (terraform.Resource, error) {
+	a, err := parseARN(s)
+	if err != nil {
+		return terraform.Resource{}, err
+	}
+
+	key := a.service + ":" + a.resourceType
+
+	// unlike application and network load balancers, the resource part of classic ones is only the name
+	if key == "elasticloadbalancing:loadbalancer" && !strings.Contains(a.resource, "/") {
+		key += "/classic"
+	}
+
+	mapping, ok := arnMappings[key]
+	if !ok {
+		return terraform.Resource{}, fmt.Errorf("unsupported ARN (service=%s, resource type=%s): %s",
+			a.service, a.resourceType, s)
+	}
+
+	if !terraform.IsType(mapping.terraformType) {
+		return terraform.Resource{}, fmt.Errorf("no resource type found: %s", mapping.terraformType)
+	}
+
+	return terraform.Resource{
+		Type:      mapping.terraformType,
+		ID:        mapping.id(a),
+		Region:    a.region,
+		AccountID: a.accountID,
+	}, nil
+}
+
+func parseARN(s string) (arn, error) {
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" || parts[2] == "" || parts[5] == "" {
+		return arn{}, fmt.Errorf("invalid ARN: %s", s)
+	}
+
+	result := arn{
+		raw:       s,
+		partition: parts[1],
+		service:   parts[2],
+		region:    parts[3],
+		accountID: parts[4],
+		resource:  parts[5],
+	}
+
+	// the resource part is either of form <resource_type>/<resource>, <resource_type>:<resource>, or <resource>
+	if i := strings.IndexAny(parts[5], "/:"); i > 0 {
+		result.resourceType = parts[5][:i]
+		result.resource = parts[5][i+1:]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromARN(t *testing.T) {
+	tests := []struct {
+		name             string
+		arn              string
+		expectedResource terraform.Resource
+		expectedErr      string
+	}{
+		{
+			name: "vpc",
+			arn:  "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1",
+			expectedResource: terraform.Resource{
+				Type: "aws_vpc", ID: "vpc-1", Region: "us-east-1", AccountID: "123456789012"},
+		},
+		{
+			name: "iam role with path",
+			arn:  "arn:aws:iam::123456789012:role/service-role/ci-runner",
+			expectedResource: terraform.Resource{
+				Type: "aws_iam_role", ID: "ci-runner", AccountID: "123456789012"},
+		},
+		{
+			name: "s3 bucket",
+			arn:  "arn:aws:s3:::my-bucket",
+			expectedResource: terraform.Resource{
+				Type: "aws_s3_bucket", ID: "my-bucket"},
+		},
+		{
+			name: "lambda function with qualifier",
+			arn:  "arn:aws:lambda:eu-west-1:123456789012:function:my-function:1",
+			expectedResource: terraform.Resource{
+				Type: "aws_lambda_function", ID: "my-function", Region: "eu-west-1", AccountID: "123456789012"},
+		},
+		{
+			name: "sqs queue",
+			arn:  "arn:aws:sqs:us-east-1:123456789012:my-queue",
+			expectedResource: terraform.Resource{
+				Type:      "aws_sqs_queue",
+				ID:        "https://sqs.us-east-1.amazonaws.com/123456789012/my-queue",
+				Region:    "us-east-1",
+				AccountID: "123456789012"},
+		},
+		{
+			name: "application load balancer",
+			arn:  "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-lb/50dc6c495c0c9188",
+			expectedResource: terraform.Resource{
+				Type:      "aws_lb",
+				ID:        "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-lb/50dc6c495c0c9188",
+				Region:    "us-east-1",
+				AccountID: "123456789012"},
+		},
+		{
+			name: "classic load balancer",
+			arn:  "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/my-lb",
+			expectedResource: terraform.Resource{
+				Type: "aws_elb", ID: "my-lb", Region: "us-east-1", AccountID: "123456789012"},
+		},
+		{
+			name: "autoscaling group",
+			arn: "arn:aws:autoscaling:us-east-1:123456789012:autoScalingGroup:" +
+				"c4c0c5a0-1f9c-4e8c-9c1a-1f3c2d4e5f6a:autoScalingGroupName/my-asg",
+			expectedResource: terraform.Resource{
+				Type: "aws_autoscaling_group", ID: "my-asg", Region: "us-east-1", AccountID: "123456789012"},
+		},
+		{
+			name:        "unsupported resource type",
+			arn:         "arn:aws:ec2:us-east-1:123456789012:foo/bar",
+			expectedErr: "unsupported ARN (service=ec2, resource type=foo)",
+		},
+		{
+			name:        "invalid ARN",
+			arn:         "arn:aws:ec2",
+			expectedErr: "invalid ARN: arn:aws:ec2",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualResource, err := FromARN(tc.arn)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResource, actualResource)
+		})
+	}
+}
+
+func TestARNMappings_AreTerraformTypes(t *testing.T) {
+	for key, mapping := range arnMappings {
+		assert.True(t, terraform.IsType(mapping.terraformType), "%s: %s", key, mapping.terraformType)
+	}
+}
+
+func TestRead_ARN(t *testing.T) {
+	actualResources, _, err := Read(strings.NewReader(
+		"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1\naws_vpc vpc-2 myaccount us-west-2\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Region: "us-east-1", AccountID: "123456789012"},
+		{Type: "aws_vpc", ID: "vpc-2", Profile: "myaccount", Region: "us-west-2"},
+	}, actualResources)
+}
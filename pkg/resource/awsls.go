@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// awslsTimeFormat is the format of the CREATED column printed by awsls.
+const awslsTimeFormat = "2006-01-02 15:04:05"
+
+// DisplayAttributes are the values of the attribute columns printed by awsls for each resource (by resourceKey).
+// They are only shown in log lines and never passed to the Terraform AWS Provider (unlike terraform.Resource.Attrs),
+// as they are formatted for display and their names might not match the schema of the resource type.
+type DisplayAttributes map[string]map[string]string
+
+// of returns the attributes printed by awsls for the given resource, if any.
+func (a DisplayAttributes) of(r terraform.Resource) map[string]string {
+	return a[resourceKey(r)]
+}
+
+// awslsHeader represents the header line printed by awsls, for example:
+// 	TYPE   ID   PROFILE   REGION   CREATED   TAGS
+// As awsls aligns columns, the header is used to locate the value of each column in the following lines.
+type awslsHeader struct {
+	names   []string
+	offsets []int
+}
+
+// isAwslsHeader returns true if the given line is a header line printed by awsls.
+func isAwslsHeader(line string) bool {
+	return strings.HasPrefix(line, "TYPE")
+}
+
+func parseAwslsHeader(line string) (*awslsHeader, error) {
+	result := &awslsHeader{}
+
+	for i, c := range line {
+		if c != ' ' && (i == 0 || line[i-1] == ' ') {
+			result.offsets = append(result.offsets, i)
+		}
+	}
+
+	result.names = strings.Fields(line)
+
+	if len(result.names) < 4 || strings.Join(result.names[:4], " ") != "TYPE ID PROFILE REGION" {
+		return nil, fmt.Errorf("header must begin with: TYPE ID PROFILE REGION")
+	}
+
+	return result, nil
+}
+
+// split returns the values of all columns in the given line. If the line is not aligned with the header,
+// false is returned.
+func (h awslsHeader) split(line string) ([]string, bool) {
+	runes := []rune(line)
+
+	result := make([]string, len(h.names))
+
+	for i, start := range h.offsets {
+		if start >= len(runes) {
+			continue
+		}
+
+		// columns are separated by at least one space
+		if start > 0 && runes[start-1] != ' ' {
+			return nil, false
+		}
+
+		end := len(runes)
+		if i+1 < len(h.offsets) && h.offsets[i+1] < end {
+			end = h.offsets[i+1]
+		}
+
+		result[i] = strings.TrimSpace(string(runes[start:end]))
+	}
+
+	return result, true
+}
+
+// setAttributes reads the values of all columns after TYPE, ID, PROFILE, and REGION (i.e., the attributes
+// printed by awsls via the -a flag) for the given resource. The creation time and tags are set on the resource,
+// other attributes are returned, as they are only shown (see DisplayAttributes).
+func (h awslsHeader) setAttributes(r *terraform.Resource, values []string) map[string]string {
+	attrs := map[string]string{}
+
+	for i := 4; i < len(h.names); i++ {
+		name := strings.ToLower(h.names[i])
+		value := values[i]
+
+		// comments (e.g., the reason written by WriteFailed) aren't attributes
+		if value == "" || value == `N/A` || strings.HasPrefix(value, "#") {
+			continue
+		}
+
+		switch name {
+		case "created":
+			createdAt, err := time.Parse(awslsTimeFormat, value)
+			if err == nil {
+				r.CreatedAt = &createdAt
+			}
+		case "tags":
+			r.Tags = parseAwslsTags(value)
+		default:
+			attrs[name] = value
+		}
+	}
+
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	return attrs
+}
+
+// parseAwslsTags parses tags printed by awsls in the form of <key>=<value>,<key>=<value>.
+func parseAwslsTags(s string) map[string]string {
+	result := map[string]string{}
+
+	var lastKey string
+
+	for _, tag := range strings.Split(s, ",") {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 {
+			// the comma was part of the previous tag value
+			if lastKey != "" {
+				result[lastKey] += "," + tag
+			}
+
+			continue
+		}
+
+		lastKey = kv[0]
+		result[kv[0]] = kv[1]
+	}
+
+	return result
+}
@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead_AwslsHeader(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 10, 11, 12, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		input             string
+		expectedResources []terraform.Resource
+		// expectedDisplayAttrs are the attributes shown for each resource, but not passed to the provider
+		expectedDisplayAttrs []map[string]string
+	}{
+		{
+			name: "with attribute columns",
+			input: `
+TYPE             ID        PROFILE     REGION      CREATED               TAGS                      INSTANCE_STATE
+aws_instance     i-1       myaccount   us-west-2   2021-03-04 10:11:12   Name=foo bar,env=test     running
+aws_instance     i-2       N/A         us-east-1   N/A                                             stopped
+`,
+			expectedResources: []terraform.Resource{
+				{
+					Type:      "aws_instance",
+					ID:        "i-1",
+					Profile:   "myaccount",
+					Region:    "us-west-2",
+					CreatedAt: &createdAt,
+					Tags:      map[string]string{"Name": "foo bar", "env": "test"},
+				},
+				{
+					Type:   "aws_instance",
+					ID:     "i-2",
+					Region: "us-east-1",
+				},
+			},
+			expectedDisplayAttrs: []map[string]string{
+				{"instance_state": "running"},
+				{"instance_state": "stopped"},
+			},
+		},
+		{
+			name: "without header (e.g., removed by grep)",
+			input: `aws_instance     i-1       myaccount   us-west-2   2021-03-04 10:11:12   Name=foo
+`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_instance", ID: "i-1", Profile: "myaccount", Region: "us-west-2"},
+			},
+		},
+		{
+			name: "line not aligned with header",
+			input: `TYPE     ID    PROFILE     REGION      TAGS
+aws_instance i-1 myaccount us-west-2 Name=foo
+`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_instance", ID: "i-1", Profile: "myaccount", Region: "us-west-2"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualResources, actualDisplayAttrs, err := Read(strings.NewReader(tc.input))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedResources, actualResources)
+
+			for i, attrs := range tc.expectedDisplayAttrs {
+				assert.Equal(t, attrs, actualDisplayAttrs.of(actualResources[i]))
+				assert.Equal(t, attrs["instance_state"],
+					logFields(actualResources[i], actualDisplayAttrs)["instance_state"])
+			}
+		})
+	}
+}
+
+func TestParseAwslsTags(t *testing.T) {
+	assert.Equal(t, map[string]string{"Name": "foo", "subnets": "a,b", "env": ""},
+		parseAwslsTags("Name=foo,subnets=a,b,env="))
+}
@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// ShowDependents lists, for each of the given resources, which of the other resources depend on it
+// (i.e., reference it directly or transitively) as a tree, and returns the number of those dependents.
+// The state of all resources must have been fetched before.
+func ShowDependents(title string, resources, others []terraform.Resource) int {
+	seen := map[string]bool{}
+	for _, r := range resources {
+		seen[resourceKey(r)] = true
+	}
+
+	all := append([]terraform.Resource{}, resources...)
+	for _, r := range others {
+		if seen[resourceKey(r)] {
+			continue
+		}
+		seen[resourceKey(r)] = true
+
+		all = append(all, r)
+	}
+
+	g := newDependencyGraph(all)
+
+	// only the other resources are listed as dependents, as the given ones are known already
+	isOther := func(i int) bool { return i >= len(resources) }
+
+	var trees [][]treeNode
+	numDependents := 0
+
+	for i := range resources {
+		visited := make([]bool, len(all))
+
+		tree := g.subtree(i, isOther, visited)
+		if len(tree) == 1 {
+			continue
+		}
+
+		trees = append(trees, tree)
+		numDependents += len(tree) - 1
+	}
+
+	if len(trees) == 0 {
+		internal.LogTitle(fmt.Sprintf("%s: none", title))
+		return 0
+	}
+
+	internal.LogTitle(fmt.Sprintf("%s: %d", title, numDependents))
+
+	for _, tree := range trees {
+		for _, n := range tree {
+			if n.depth == 0 {
+				log.WithFields(IdentityFields(n.resource)).Info(internal.Pad(formatTreeNode(n)))
+				continue
+			}
+
+			log.WithFields(logFields(n.resource, nil)).Warn(internal.Pad(formatTreeNode(n)))
+		}
+	}
+
+	return numDependents
+}
@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestShowDependents(t *testing.T) {
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{})
+	role := withState("aws_iam_role", "my-role", map[string]cty.Value{
+		"arn": cty.StringVal("arn:aws:iam::123456789012:role/my-role"),
+	})
+	instance := withState("aws_instance", "i-1", map[string]cty.Value{
+		"vpc_security_group_ids": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+	})
+	function := withState("aws_lambda_function", "my-function", map[string]cty.Value{
+		"role": cty.StringVal("arn:aws:iam::123456789012:role/my-role"),
+		"vpc_config": cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"security_group_ids": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+		})}),
+	})
+	launchTemplate := withState("aws_launch_template", "lt-1", map[string]cty.Value{
+		"iam_instance_profile": cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"arn": cty.StringVal("arn:aws:iam::123456789012:instance-profile/my-profile"),
+		})}),
+	})
+	autoscalingGroup := withState("aws_autoscaling_group", "my-asg", map[string]cty.Value{
+		"launch_template": cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"id":   cty.StringVal("lt-1"),
+			"name": cty.StringVal("my-template"),
+		})}),
+	})
+	instanceProfile := withState("aws_iam_instance_profile", "my-profile", map[string]cty.Value{
+		"arn":  cty.StringVal("arn:aws:iam::123456789012:instance-profile/my-profile"),
+		"role": cty.StringVal("my-role"),
+	})
+
+	tests := []struct {
+		name      string
+		resources []terraform.Resource
+		others    []terraform.Resource
+		expected  int
+	}{
+		{
+			name:      "security group",
+			resources: []terraform.Resource{securityGroup},
+			others:    []terraform.Resource{role, instance, function},
+			expected:  2,
+		},
+		{
+			name:      "role with transitive dependents",
+			resources: []terraform.Resource{role},
+			others:    []terraform.Resource{instanceProfile, launchTemplate, autoscalingGroup, instance, function},
+			expected:  4,
+		},
+		{
+			name:      "dependents that are deleted as well are not listed",
+			resources: []terraform.Resource{securityGroup, instance},
+			others:    []terraform.Resource{instance},
+			expected:  0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ShowDependents("dependents", tc.resources, tc.others))
+		})
+	}
+}
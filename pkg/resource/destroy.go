@@ -0,0 +1,330 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awstools-lib/terraform"
+	terradozerRes "github.com/jckuester/terradozer/pkg/resource"
+)
+
+// parallelDeletions is the number of resources of a layer that are deleted in parallel.
+const parallelDeletions = 5
+
+var (
+	// initialRetryBackoff is the time to wait before the first retry, which is doubled for each following retry.
+	initialRetryBackoff = 5 * time.Second
+	maxRetryBackoff     = 1 * time.Minute
+)
+
+// retryableErrors are parts of (lowercase) error messages for which deleting a resource might succeed in a later
+// pass, for example, when a resource is still in use by another one that is being deleted, or requests are throttled.
+var retryableErrors = []string{
+	"dependencyviolation",
+	"dependent object",
+	"in use",
+	"inuse",
+	"resourceconflict",
+	"deleteconflict",
+	"throttl",
+	"requestlimitexceeded",
+	"toomanyrequests",
+	"rate exceeded",
+	"serviceunavailable",
+	"internalerror",
+	"timeout",
+	"timed out",
+	"try again",
+}
+
+// ErrInterrupted is the reason why a resource hasn't been deleted if deleting has been interrupted before.
+var ErrInterrupted = errors.New("deletion interrupted")
+
+// destroy deletes a resource (replaced in tests).
+var destroy = defaultDestroy
+
+// defaultDestroy deletes a resource via the Terraform AWS Provider.
+func defaultDestroy(r terraform.Resource) error {
+	err := terradozerRes.Resource{Resource: r}.Destroy()
+
+	var retryErr *terradozerRes.RetryDestroyError
+	if errors.As(err, &retryErr) {
+		return retryErr.Err
+	}
+
+	return err
+}
+
+// FailedResource is a resource that couldn't be deleted.
+type FailedResource struct {
+	terraform.Resource
+	// Err is the reason why the resource couldn't be deleted.
+	Err error
+	// duration is the time the last attempt to delete the resource took.
+	duration time.Duration
+}
+
+// dependentNotDeletedError is the reason why a resource wasn't deleted in a pass:
+// a resource that depends on it couldn't be deleted before.
+type dependentNotDeletedError struct {
+	dependent terraform.Resource
+}
+
+func (e dependentNotDeletedError) Error() string {
+	return fmt.Sprintf("resource depending on it couldn't be deleted: %s %s", e.dependent.Type, e.dependent.ID)
+}
+
+// isRetryable returns true if deleting a resource failed for a reason that might go away in a later pass.
+func isRetryable(err error) bool {
+	var notDeleted dependentNotDeletedError
+	if errors.As(err, &notDeleted) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, s := range retryableErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// destroyResources deletes the given resources in dependency order. Resources that fail to be deleted
+// for a retryable reason (e.g., DependencyViolation or throttling) are retried in later passes with exponential
+// backoff, until all are deleted, a pass deletes none of them, or the maximum number of retries is reached.
+//
+// If BeforeDestroy of the options is not nil, it is called for each resource just before each attempt to delete it;
+// if it returns an error, the resource isn't deleted in that attempt.
+// Once Interrupted of the options is closed, no further resources are attempted to delete; they fail
+// with ErrInterrupted.
+func destroyResources(resources []terraform.Resource, opts DeleteOptions) ([]terraform.Resource, []FailedResource) {
+	var deleted []terraform.Resource
+	var failed []FailedResource
+
+	pending := resources
+	backoff := initialRetryBackoff
+
+	for retry := 0; ; retry++ {
+		deletedInPass, failedInPass := destroyPass(pending, opts.BeforeDestroy, opts.Interrupted)
+		deleted = append(deleted, deletedInPass...)
+
+		retryable, notRetryable := splitRetryable(failedInPass)
+
+		if len(retryable) != 0 && (retry == opts.MaxRetries || (retry > 0 && len(deletedInPass) == 0) ||
+			isInterrupted(opts.Interrupted)) {
+			notRetryable = append(notRetryable, retryable...)
+			retryable = nil
+		}
+
+		for _, f := range notRetryable {
+			emitEvent(failedEvent(EventFailed, f))
+		}
+		failed = append(failed, notRetryable...)
+
+		if len(retryable) == 0 {
+			break
+		}
+
+		for _, f := range retryable {
+			emitEvent(failedEvent(EventRetrying, f))
+		}
+
+		internal.LogTitle(fmt.Sprintf("retrying to delete %d resource(s) in %s (retry %d of %d)",
+			len(retryable), backoff, retry+1, opts.MaxRetries))
+
+		for _, f := range retryable {
+			log.WithFields(IdentityFields(f.Resource)).WithField("error", formatError(f.Err)).
+				Info(internal.Pad(f.Type))
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-opts.Interrupted:
+			// the retryable resources are left to the next pass, which doesn't delete anything anymore
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+
+		pending = nil
+		for _, f := range retryable {
+			pending = append(pending, f.Resource)
+		}
+	}
+
+	return deleted, failed
+}
+
+// splitRetryable splits the given resources that failed to be deleted into the ones that are worth retrying
+// and the ones that are not. A resource that wasn't deleted because a resource depending on it failed
+// is only retried if that one is retried as well.
+func splitRetryable(failed []FailedResource) ([]FailedResource, []FailedResource) {
+	retry := make([]bool, len(failed))
+	for i, f := range failed {
+		retry[i] = isRetryable(f.Err)
+	}
+
+	index := map[string]int{}
+	for i, f := range failed {
+		index[resourceKey(f.Resource)] = i
+	}
+
+	// resources can wait for each other in a chain (e.g., a VPC for a security group for an instance)
+	for changed := true; changed; {
+		changed = false
+
+		for i, f := range failed {
+			var notDeleted dependentNotDeletedError
+			if !retry[i] || !errors.As(f.Err, &notDeleted) {
+				continue
+			}
+
+			if j, ok := index[resourceKey(notDeleted.dependent)]; ok && !retry[j] {
+				retry[i] = false
+				changed = true
+			}
+		}
+	}
+
+	var retryable, notRetryable []FailedResource
+	for i, f := range failed {
+		if retry[i] {
+			retryable = append(retryable, f)
+		} else {
+			notRetryable = append(notRetryable, f)
+		}
+	}
+
+	return retryable, notRetryable
+}
+
+// destroyPass deletes the given resources once, layer by layer in dependency order, where the resources
+// of each layer are deleted in parallel. A resource is not deleted if a resource depending on it failed to be deleted.
+func destroyPass(resources []terraform.Resource, beforeDestroy func(terraform.Resource) error,
+	interrupted <-chan struct{}) ([]terraform.Resource, []FailedResource) {
+	g := newDependencyGraph(resources)
+	layers, _ := g.deletionLayerIndices()
+
+	errs := make([]error, len(resources))
+	durations := make([]time.Duration, len(resources))
+
+	for n, layer := range layers {
+		log.WithField("resources", len(layer)).Debugf("deleting layer %d of %d", n+1, len(layers))
+
+		// resources depending on the ones of this layer have been attempted to delete in previous layers
+		var toDelete []int
+		blocked := map[int]int{}
+
+		for _, i := range layer {
+			if j, ok := failedDependent(g, i, errs); ok {
+				blocked[i] = j
+				continue
+			}
+			toDelete = append(toDelete, i)
+		}
+
+		for i, j := range blocked {
+			errs[i] = dependentNotDeletedError{dependent: resources[j]}
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, parallelDeletions)
+
+		for _, i := range toDelete {
+			// deletions in progress are finished, but no new ones are started after an interrupt
+			if isInterrupted(interrupted) {
+				errs[i] = ErrInterrupted
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if beforeDestroy != nil {
+					errs[i] = beforeDestroy(resources[i])
+					if errs[i] != nil {
+						return
+					}
+				}
+
+				EmitEvent(EventDeleting, resources[i])
+
+				start := time.Now()
+				errs[i] = destroy(resources[i])
+				durations[i] = time.Since(start)
+
+				if errs[i] == nil {
+					e := newEvent(EventDeleted, resources[i])
+					e.DurationMs = durations[i].Milliseconds()
+					emitEvent(e)
+				}
+			}(i)
+		}
+
+		wg.Wait()
+	}
+
+	var deleted []terraform.Resource
+	var failed []FailedResource
+
+	for i, r := range resources {
+		if errs[i] != nil {
+			log.WithFields(IdentityFields(r)).WithError(errs[i]).Debug(internal.Pad("failed to delete resource"))
+
+			failed = append(failed, FailedResource{Resource: r, Err: errs[i], duration: durations[i]})
+			continue
+		}
+
+		deleted = append(deleted, r)
+	}
+
+	return deleted, failed
+}
+
+// isInterrupted returns true if the given channel is closed.
+func isInterrupted(interrupted <-chan struct{}) bool {
+	select {
+	case <-interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// failedDependent returns the index of a resource that depends on the resource of index i and failed to be deleted.
+func failedDependent(g *dependencyGraph, i int, errs []error) (int, bool) {
+	for _, j := range g.dependents[i] {
+		if errs[j] != nil {
+			return j, true
+		}
+	}
+
+	return 0, false
+}
+
+func failedEvent(name string, f FailedResource) Event {
+	e := newEvent(name, f.Resource)
+	e.Error = formatError(f.Err)
+	e.DurationMs = f.duration.Milliseconds()
+
+	return e
+}
+
+// formatError returns the error message on a single line, as errors of the Terraform AWS Provider span multiple lines.
+func formatError(err error) string {
+	return strings.Join(strings.Fields(err.Error()), " ")
+}
@@ -0,0 +1,253 @@
+package resource
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeDestroy fails to delete each resource with the given error for the given number of attempts.
+type fakeDestroy struct {
+	mu       sync.Mutex
+	failures map[string][]error
+	attempts []string
+}
+
+func (f *fakeDestroy) destroy(r terraform.Resource) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.attempts = append(f.attempts, r.ID)
+
+	if len(f.failures[r.ID]) == 0 {
+		return nil
+	}
+
+	err := f.failures[r.ID][0]
+	f.failures[r.ID] = f.failures[r.ID][1:]
+
+	return err
+}
+
+func TestDestroyResources(t *testing.T) {
+	dependencyViolation := errors.New("DependencyViolation: resource sg-1 has a dependent object")
+	throttled := errors.New("Throttling: Rate exceeded")
+	accessDenied := errors.New("UnauthorizedOperation: You are not authorized to perform this operation")
+
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+	instance := withState("aws_instance", "i-1", map[string]cty.Value{
+		"vpc_security_group_ids": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+	})
+	role := withState("aws_iam_role", "my-role", map[string]cty.Value{})
+
+	tests := []struct {
+		name             string
+		failures         map[string][]error
+		maxRetries       int
+		expectedDeleted  []string
+		expectedFailed   map[string]string
+		expectedAttempts []string
+	}{
+		{
+			name:             "no failures",
+			maxRetries:       3,
+			expectedDeleted:  []string{"i-1", "my-role", "sg-1", "vpc-1"},
+			expectedAttempts: []string{"i-1", "my-role", "sg-1", "vpc-1"},
+		},
+		{
+			name: "retry dependency violation and throttling",
+			failures: map[string][]error{
+				"sg-1":    {dependencyViolation},
+				"my-role": {throttled, throttled},
+			},
+			maxRetries:      3,
+			expectedDeleted: []string{"i-1", "sg-1", "vpc-1", "my-role"},
+			expectedAttempts: []string{
+				// first pass: the VPC isn't attempted, as its security group failed
+				"i-1", "my-role", "sg-1",
+				"my-role", "sg-1", "vpc-1",
+				"my-role",
+			},
+		},
+		{
+			name: "max retries exceeded",
+			failures: map[string][]error{
+				"my-role": {throttled, throttled},
+			},
+			maxRetries:      1,
+			expectedDeleted: []string{"i-1", "sg-1", "vpc-1"},
+			expectedFailed:  map[string]string{"my-role": throttled.Error()},
+		},
+		{
+			name: "no progress",
+			failures: map[string][]error{
+				"sg-1": {dependencyViolation, dependencyViolation, dependencyViolation},
+			},
+			maxRetries:      5,
+			expectedDeleted: []string{"i-1", "my-role"},
+			expectedFailed: map[string]string{
+				"sg-1":  dependencyViolation.Error(),
+				"vpc-1": "resource depending on it couldn't be deleted: aws_security_group sg-1",
+			},
+		},
+		{
+			name: "not retryable",
+			failures: map[string][]error{
+				"i-1": {accessDenied},
+			},
+			maxRetries:      3,
+			expectedDeleted: []string{"my-role"},
+			expectedFailed: map[string]string{
+				"i-1":   accessDenied.Error(),
+				"sg-1":  "resource depending on it couldn't be deleted: aws_instance i-1",
+				"vpc-1": "resource depending on it couldn't be deleted: aws_security_group sg-1",
+			},
+		},
+	}
+
+	initialRetryBackoff = time.Millisecond
+	defer func() { initialRetryBackoff = 5 * time.Second }()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeDestroy{failures: tc.failures}
+
+			destroy = fake.destroy
+			defer func() { destroy = defaultDestroy }()
+
+			deleted, failed := destroyResources([]terraform.Resource{vpc, securityGroup, instance, role},
+				DeleteOptions{MaxRetries: tc.maxRetries})
+
+			var deletedIDs []string
+			for _, r := range deleted {
+				deletedIDs = append(deletedIDs, r.ID)
+			}
+			assert.ElementsMatch(t, tc.expectedDeleted, deletedIDs)
+
+			failedIDs := map[string]string{}
+			for _, f := range failed {
+				failedIDs[f.ID] = f.Err.Error()
+			}
+			if tc.expectedFailed == nil {
+				tc.expectedFailed = map[string]string{}
+			}
+			assert.Equal(t, tc.expectedFailed, failedIDs)
+
+			if tc.expectedAttempts != nil {
+				assert.ElementsMatch(t, tc.expectedAttempts, fake.attempts)
+			}
+		})
+	}
+}
+
+func TestDestroyResources_BeforeDestroy(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+
+	fake := &fakeDestroy{}
+
+	destroy = fake.destroy
+	defer func() { destroy = defaultDestroy }()
+
+	var calls []string
+	beforeDestroy := func(r terraform.Resource) error {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		// the resource must not have been attempted to delete yet
+		assert.NotContains(t, fake.attempts, r.ID)
+		calls = append(calls, r.ID)
+
+		return nil
+	}
+
+	_, failed := destroyResources([]terraform.Resource{vpc, securityGroup}, DeleteOptions{BeforeDestroy: beforeDestroy})
+	require.Empty(t, failed)
+
+	assert.Equal(t, []string{"sg-1", "vpc-1"}, calls)
+}
+
+func TestDestroyResources_BeforeDestroyFails(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+
+	fake := &fakeDestroy{}
+
+	destroy = fake.destroy
+	defer func() { destroy = defaultDestroy }()
+
+	beforeDestroy := func(r terraform.Resource) error {
+		if r.ID == "sg-1" {
+			return errors.New("failed to tag resource")
+		}
+		return nil
+	}
+
+	deleted, failed := destroyResources([]terraform.Resource{vpc, securityGroup},
+		DeleteOptions{BeforeDestroy: beforeDestroy})
+	assert.Empty(t, deleted)
+
+	failedIDs := map[string]string{}
+	for _, f := range failed {
+		failedIDs[f.ID] = f.Err.Error()
+	}
+
+	assert.Equal(t, map[string]string{
+		"sg-1":  "failed to tag resource",
+		"vpc-1": "resource depending on it couldn't be deleted: aws_security_group sg-1",
+	}, failedIDs)
+
+	// a resource that failed before deletion isn't attempted to delete
+	assert.Empty(t, fake.attempts)
+}
+
+func TestDestroyResources_Interrupted(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+
+	interrupted := make(chan struct{})
+
+	fake := &fakeDestroy{}
+
+	// awsrm is interrupted while the security group is being deleted
+	destroy = func(r terraform.Resource) error {
+		close(interrupted)
+		return fake.destroy(r)
+	}
+	defer func() { destroy = defaultDestroy }()
+
+	deleted, failed := destroyResources([]terraform.Resource{vpc, securityGroup},
+		DeleteOptions{MaxRetries: 3, Interrupted: interrupted})
+
+	assert.Equal(t, []terraform.Resource{securityGroup}, deleted)
+
+	require.Len(t, failed, 1)
+	assert.Equal(t, "vpc-1", failed[0].ID)
+	assert.Equal(t, ErrInterrupted, failed[0].Err)
+
+	assert.Equal(t, []string{"sg-1"}, fake.attempts)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(errors.New("DependencyViolation: The vpc 'vpc-1' has dependencies and cannot be deleted.")))
+	assert.True(t, isRetryable(errors.New("error deleting IAM Role: DeleteConflict: Cannot delete entity, must "+
+		"detach all policies first. ResourceInUse")))
+	assert.True(t, isRetryable(errors.New("RequestLimitExceeded: Request limit exceeded.")))
+	assert.True(t, isRetryable(dependentNotDeletedError{}))
+	assert.False(t, isRetryable(errors.New("UnauthorizedOperation: You are not authorized to perform this operation.")))
+}
@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// editPlanHelp is written on top of the file of resources to delete, which is opened in an editor.
+const editPlanHelp = `# Resources to delete (one per line: <resource_type> <resource_id> <profile> <region>).
+#
+# Remove or comment out (#) the lines of resources that should NOT be deleted.
+# Only resources of this list can be deleted; any other line is rejected.
+# If you remove all lines, nothing will be deleted.
+#
+# Keep the header line (TYPE ID ...) and the alignment of the columns, which locate the IDs that contain spaces.
+#
+`
+
+// selectInEditor writes the given resources into a temporary file, opens it in the editor of the user
+// (i.e., $VISUAL, $EDITOR, or vi), and returns the resources that are still listed after the editor is closed.
+//
+// The edited list is validated against the given resources, so that no other resources can be added.
+func selectInEditor(resources []terraform.Resource, displayAttrs DisplayAttributes,
+	tty io.Reader) ([]terraform.Resource, error) {
+	f, err := ioutil.TempFile("", "awsrm-plan-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(editPlanHelp + formatPlan(resources, displayAttrs))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = runEditor(f.Name(), tty)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := os.Open(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer edited.Close()
+
+	return parsePlan(edited, resources)
+}
+
+// formatPlan formats each resource as a line of the pipe format, followed by its attributes as a comment.
+func formatPlan(resources []terraform.Resource, displayAttrs DisplayAttributes) string {
+	var b bytes.Buffer
+
+	w := tabwriter.NewWriter(&b, 0, 8, 3, ' ', 0)
+
+	// the header locates the columns when reading the edited list, as IDs can contain spaces
+	fmt.Fprintln(w, "TYPE\tID\tPROFILE\tREGION\tATTRIBUTES")
+
+	for _, r := range resources {
+		profile := r.Profile
+		if profile == "" {
+			profile = `N/A`
+		}
+
+		// the region column is always terminated, so that all lines are aligned with the header
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t", r.Type, r.ID, profile, r.Region)
+
+		fields := logFields(r, displayAttrs)
+		for name := range IdentityFields(r) {
+			delete(fields, name)
+		}
+
+		var attrs []string
+		for name, value := range fields {
+			attrs = append(attrs, fmt.Sprintf("%s=%v", name, value))
+		}
+		sort.Strings(attrs)
+
+		if r.AccountID != "" {
+			attrs = append([]string{"account=" + r.AccountID}, attrs...)
+		}
+
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, "# %s", strings.Join(attrs, " "))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	_ = w.Flush()
+
+	return trimTrailingSpaces(b.String())
+}
+
+// parsePlan reads the edited list of resources and returns the matching resources of the given ones.
+// An error is returned if a line is invalid or lists a resource that isn't one of the given ones.
+func parsePlan(r io.Reader, resources []terraform.Resource) ([]terraform.Resource, error) {
+	type key struct {
+		rType, id, profile, region string
+	}
+
+	planned := map[key]terraform.Resource{}
+	for _, r := range resources {
+		planned[key{r.Type, r.ID, r.Profile, r.Region}] = r
+	}
+
+	edited, err := readPlan(r)
+	if err != nil {
+		var lineErrs LineErrors
+		if errors.As(err, &lineErrs) {
+			return nil, fmt.Errorf("invalid line(s) in edited list of resources:\n%s", err)
+		}
+		return nil, err
+	}
+
+	var result []terraform.Resource
+
+	for _, e := range RemoveDuplicates(edited) {
+		r, ok := planned[key{e.Type, e.ID, e.Profile, e.Region}]
+		if !ok {
+			return nil, fmt.Errorf("resource is not in the list of resources to delete: %s %s (profile=%s, region=%s)",
+				e.Type, e.ID, e.Profile, e.Region)
+		}
+
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+// readPlan reads the resources of the edited list. Like Read, the values of each line are located
+// by the columns of the header line (if the line is still aligned with it), but the attributes are ignored.
+func readPlan(r io.Reader) ([]terraform.Resource, error) {
+	var result []terraform.Resource
+	var header *awslsHeader
+	var lineErrs LineErrors
+
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		// ignore empty lines and comments
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if isAwslsHeader(line) {
+			var err error
+
+			header, err = parseAwslsHeader(line)
+			if err != nil {
+				lineErrs = append(lineErrs, LineError{Line: lineNumber, Text: line, Err: err})
+			}
+
+			continue
+		}
+
+		values, _ := splitLine(line, header)
+
+		e, err := resourceFromValues(values)
+		if err != nil {
+			lineErrs = append(lineErrs, LineError{Line: lineNumber, Text: line, Err: err})
+			continue
+		}
+
+		result = append(result, e)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lineErrs) > 0 {
+		return nil, lineErrs
+	}
+
+	return result, nil
+}
+
+// runEditor opens the given file in the editor of the user and waits until the editor is closed.
+func runEditor(path string, tty io.Reader) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor setting can contain arguments (e.g., "code --wait")
+	args := append(strings.Fields(editor), path)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+
+	// the editor is shown on the terminal, as stdin might be a file (e.g., in argument mode) and stdout
+	// might be events; without a terminal, the editor reads from the confirmation device and writes to stderr
+	terminal, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err == nil {
+		defer terminal.Close()
+
+		cmd.Stdin = terminal
+		cmd.Stdout = terminal
+	} else {
+		cmd.Stdin = tty
+		cmd.Stdout = os.Stderr
+	}
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("failed to run editor %s: %s", editor, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPlan(t *testing.T) {
+	resources := []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1"},
+		{Type: "aws_instance", ID: "i-1", Region: "us-east-1", Tags: map[string]string{"Name": "foo"}},
+	}
+
+	displayAttrs := DisplayAttributes{resourceKey(resources[1]): {"instance_state": "running"}}
+
+	expected := `TYPE           ID      PROFILE   REGION      ATTRIBUTES
+aws_vpc        vpc-1   dev       us-east-1
+aws_instance   i-1     N/A       us-east-1   # instance_state=running tags=Name=foo
+`
+
+	assert.Equal(t, expected, formatPlan(resources, displayAttrs))
+}
+
+func TestParsePlan(t *testing.T) {
+	resources := []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "dev", Region: "us-east-1"},
+		{Type: "aws_vpc", ID: "vpc-2", Profile: "dev", Region: "us-east-1"},
+		{Type: "aws_instance", ID: "i-1", Region: "us-east-1", Tags: map[string]string{"Name": "foo"}},
+		{Type: "aws_iam_policy", ID: "my policy", Profile: "dev", Region: "us-east-1"},
+	}
+
+	tests := []struct {
+		name        string
+		edited      string
+		expected    []terraform.Resource
+		expectedErr string
+	}{
+		{
+			name:     "unchanged",
+			edited:   editPlanHelp + formatPlan(resources, nil),
+			expected: resources,
+		},
+		{
+			name: "removed and commented out lines",
+			edited: editPlanHelp + `aws_vpc vpc-1 dev us-east-1
+# aws_vpc vpc-2 dev us-east-1
+`,
+			expected: resources[:1],
+		},
+		{
+			name: "ID with spaces",
+			edited: editPlanHelp + `TYPE             ID          PROFILE   REGION      ATTRIBUTES
+# aws_vpc        vpc-1       dev       us-east-1
+aws_iam_policy   my policy   dev       us-east-1
+`,
+			expected: resources[3:],
+		},
+		{
+			name:   "all lines removed",
+			edited: editPlanHelp,
+		},
+		{
+			name:        "resource added",
+			edited:      "aws_vpc vpc-3 dev us-east-1\n",
+			expectedErr: "resource is not in the list of resources to delete: aws_vpc vpc-3 (profile=dev, region=us-east-1)",
+		},
+		{
+			name:        "resource moved to other profile",
+			edited:      "aws_vpc vpc-1 prod us-east-1\n",
+			expectedErr: "resource is not in the list of resources to delete: aws_vpc vpc-1 (profile=prod, region=us-east-1)",
+		},
+		{
+			name:   "invalid line",
+			edited: "aws_vpc vpc-1\n",
+			expectedErr: "invalid line(s) in edited list of resources:\n" +
+				"line 1: line must be of form: <resource_type> <resource_id> <profile> <region>: aws_vpc vpc-1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parsePlan(strings.NewReader(tc.edited), resources)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
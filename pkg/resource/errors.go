@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineError describes why a line of input couldn't be read.
+type LineError struct {
+	// Line is the line number (starting at 1).
+	Line int
+	// Text is the offending input.
+	Text string
+	// Err is the reason why the input is invalid.
+	Err error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Err, e.Text)
+}
+
+// LineErrors is returned by Read if one or more lines of input couldn't be read.
+type LineErrors []LineError
+
+func (e LineErrors) Error() string {
+	var result []string
+
+	for _, err := range e {
+		result = append(result, err.Error())
+	}
+
+	return strings.Join(result, "\n")
+}
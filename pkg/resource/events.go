@@ -0,0 +1,150 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// EventFormat is the format in which events are written.
+type EventFormat string
+
+const (
+	// EventsJSON writes all events as a single JSON array after the run.
+	EventsJSON EventFormat = "json"
+	// EventsNDJSON writes each event as a JSON object on a single line as soon as it happens.
+	EventsNDJSON EventFormat = "ndjson"
+)
+
+// Names of events, in the order in which they happen for a resource.
+const (
+	EventInputParsed      = "input_parsed"
+	EventStateFetched     = "state_fetched"
+	EventStateFetchFailed = "state_fetch_failed"
+	EventSkipped          = "skipped"
+	EventAlreadyDeleted   = "already_deleted"
+	EventProtected        = "protected"
+	EventPlanned          = "planned"
+	EventConfirmed        = "confirmed"
+	EventAborted          = "aborted"
+	EventDeleting         = "deleting"
+	EventRetrying         = "retrying"
+	EventDeleted          = "deleted"
+	EventFailed           = "failed"
+)
+
+// Event is a machine-readable event of a stage of deleting resources. Events of a stage that concern a single
+// resource identify the resource; other events (e.g., aborted) only have a name and time.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Type    string    `json:"type,omitempty"`
+	ID      string    `json:"id,omitempty"`
+	Profile string    `json:"profile,omitempty"`
+	Region  string    `json:"region,omitempty"`
+	Account string    `json:"account,omitempty"`
+	// Reason is why a resource is skipped (e.g., protected) or, for confirmed resources, why they are deleted.
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// DurationMs is the time it took to delete a resource (or to fail to do so) in milliseconds.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+// events is where events are written; no events are written unless an output is set.
+var events = struct {
+	sync.Mutex
+	w        io.Writer
+	format   EventFormat
+	buffered []Event
+}{}
+
+// SetEventOutput sets where and in which format events are written.
+func SetEventOutput(w io.Writer, format EventFormat) {
+	events.Lock()
+	defer events.Unlock()
+
+	events.w = w
+	events.format = format
+	events.buffered = nil
+}
+
+// ParseEventFormat returns the event format of the given name.
+func ParseEventFormat(s string) (EventFormat, error) {
+	switch EventFormat(s) {
+	case EventsJSON, EventsNDJSON:
+		return EventFormat(s), nil
+	}
+
+	return "", fmt.Errorf("unknown output format: %s (must be one of text, json, ndjson)", s)
+}
+
+// FlushEvents writes the events buffered for the JSON format.
+func FlushEvents() error {
+	events.Lock()
+	defer events.Unlock()
+
+	if events.w == nil || events.format != EventsJSON {
+		return nil
+	}
+
+	result := events.buffered
+	if result == nil {
+		result = []Event{}
+	}
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(events.w, string(out))
+
+	events.buffered = nil
+
+	return err
+}
+
+// EmitEvent writes an event of the given name for a resource.
+func EmitEvent(name string, r terraform.Resource) {
+	emitEvent(newEvent(name, r))
+}
+
+func newEvent(name string, r terraform.Resource) Event {
+	return Event{
+		Event:   name,
+		Type:    r.Type,
+		ID:      r.ID,
+		Profile: r.Profile,
+		Region:  r.Region,
+		Account: r.AccountID,
+	}
+}
+
+func emitEvent(e Event) {
+	events.Lock()
+	defer events.Unlock()
+
+	if events.w == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	if events.format == EventsJSON {
+		events.buffered = append(events.buffered, e)
+		return
+	}
+
+	out, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintln(events.w, string(out))
+}
@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestEmitEvent_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	SetEventOutput(&buf, EventsNDJSON)
+	defer SetEventOutput(nil, "")
+
+	EmitEvent(EventPlanned, terraform.Resource{
+		Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-west-2", AccountID: "123456789012"})
+	emitEvent(Event{Event: EventAborted})
+
+	require.NoError(t, FlushEvents())
+
+	var actual []Event
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+
+		assert.False(t, e.Time.IsZero())
+		e.Time = time.Time{}
+
+		actual = append(actual, e)
+	}
+
+	assert.Equal(t, []Event{
+		{Event: EventPlanned, Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-west-2",
+			Account: "123456789012"},
+		{Event: EventAborted},
+	}, actual)
+}
+
+func TestEmitEvent_JSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	SetEventOutput(&buf, EventsJSON)
+	defer SetEventOutput(nil, "")
+
+	EmitEvent(EventPlanned, terraform.Resource{Type: "aws_vpc", ID: "vpc-1"})
+	EmitEvent(EventConfirmed, terraform.Resource{Type: "aws_vpc", ID: "vpc-1"})
+
+	// events are only written when flushed
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, FlushEvents())
+
+	var actual []Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &actual))
+
+	require.Len(t, actual, 2)
+	assert.Equal(t, EventPlanned, actual[0].Event)
+	assert.Equal(t, EventConfirmed, actual[1].Event)
+}
+
+func TestDestroyResources_Events(t *testing.T) {
+	var buf bytes.Buffer
+
+	SetEventOutput(&buf, EventsNDJSON)
+	defer SetEventOutput(nil, "")
+
+	initialRetryBackoff = time.Millisecond
+	defer func() { initialRetryBackoff = 5 * time.Second }()
+
+	fake := &fakeDestroy{failures: map[string][]error{
+		"sg-1": {errors.New("DependencyViolation"), errors.New("DependencyViolation")},
+	}}
+	destroy = fake.destroy
+	defer func() { destroy = defaultDestroy }()
+
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+
+	destroyResources([]terraform.Resource{vpc, securityGroup}, DeleteOptions{MaxRetries: 1})
+
+	actual := map[string][]string{}
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+
+		actual[e.ID] = append(actual[e.ID], e.Event)
+
+		if e.Event == EventFailed && e.ID == "sg-1" {
+			assert.Equal(t, "DependencyViolation", e.Error)
+		}
+	}
+
+	assert.Equal(t, map[string][]string{
+		"sg-1":  {EventDeleting, EventRetrying, EventDeleting, EventFailed},
+		"vpc-1": {EventRetrying, EventFailed},
+	}, actual)
+}
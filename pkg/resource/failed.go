@@ -0,0 +1,45 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteFailed writes each resource that couldn't be deleted as a line of the pipe format
+// (<type> <id> <profile> <region>), followed by the reason as a comment, so that the output
+// can be read again via Read to retry deleting the resources. Like the header printed by awsls,
+// a header line locates the columns when reading the output, as IDs can contain spaces.
+func WriteFailed(w io.Writer, failed []FailedResource) error {
+	var b bytes.Buffer
+
+	tw := tabwriter.NewWriter(&b, 0, 8, 3, ' ', 0)
+
+	fmt.Fprintln(tw, "TYPE\tID\tPROFILE\tREGION\tERROR")
+
+	for _, f := range failed {
+		profile := f.Profile
+		if profile == "" {
+			profile = `N/A`
+		}
+
+		// the region column is always terminated, so that all lines are aligned with the header
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t", f.Type, f.ID, profile, f.Region)
+
+		if f.Err != nil {
+			fmt.Fprintf(tw, "# %s", formatError(f.Err))
+		}
+
+		fmt.Fprintln(tw)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, trimTrailingSpaces(b.String()))
+
+	return err
+}
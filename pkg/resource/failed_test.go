@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFailed(t *testing.T) {
+	failed := []FailedResource{
+		{
+			Resource: terraform.Resource{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+			Err:      errors.New("DependencyViolation: The vpc 'vpc-1' has dependencies\nand cannot be deleted."),
+		},
+		{
+			Resource: terraform.Resource{Type: "aws_iam_role", ID: "my-role", Region: "us-east-1"},
+		},
+		{
+			Resource: terraform.Resource{Type: "aws_iam_role", ID: "my role", Region: "us-east-1"},
+			Err:      errors.New("NoSuchEntity: The role with name my role cannot be found."),
+		},
+	}
+
+	var buf bytes.Buffer
+
+	err := WriteFailed(&buf, failed)
+	require.NoError(t, err)
+
+	assert.Equal(t, "TYPE           ID        PROFILE     REGION      ERROR\n"+
+		"aws_vpc        vpc-1     myaccount   us-east-1   "+
+		"# DependencyViolation: The vpc 'vpc-1' has dependencies and cannot be deleted.\n"+
+		"aws_iam_role   my-role   N/A         us-east-1\n"+
+		"aws_iam_role   my role   N/A         us-east-1   "+
+		"# NoSuchEntity: The role with name my role cannot be found.\n", buf.String())
+
+	actual, actualDisplayAttrs, err := Read(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+		{Type: "aws_iam_role", ID: "my-role", Region: "us-east-1"},
+		{Type: "aws_iam_role", ID: "my role", Region: "us-east-1"},
+	}, actual)
+	assert.Empty(t, actualDisplayAttrs)
+}
@@ -0,0 +1,493 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Filter selects resources by the attributes of their Terraform state. A resource matches a filter
+// only if it matches all tags, absent tags, conditions, and the age limits.
+type Filter struct {
+	Tags       map[string]string
+	TagsAbsent []string
+	Conditions []Condition
+	// OlderThan selects resources created more than the given duration ago (zero means no limit).
+	OlderThan time.Duration
+	// NewerThan selects resources created less than the given duration ago (zero means no limit).
+	NewerThan time.Duration
+	// IncludeUnknownAge keeps resources without a known creation time if there are age limits
+	// (by default, they don't match).
+	IncludeUnknownAge bool
+}
+
+// timestampAttributes are the names of attributes in the Terraform state of a resource
+// that contain its creation time.
+var timestampAttributes = []string{
+	"launch_time",
+	"create_date",
+	"creation_date",
+	"created_time",
+	"created_date",
+	"create_time",
+	"creation_time",
+}
+
+// Condition compares an attribute of the Terraform state of a resource with a value.
+type Condition struct {
+	// Attribute is the name of a top-level attribute or a dot-separated path to a nested one (e.g., tags.Name).
+	Attribute string
+	// Operator is one of =, !=, =~, !~, <, <=, >, >=.
+	Operator string
+	Value    string
+
+	regexp *regexp.Regexp
+}
+
+var conditionRegexp = regexp.MustCompile(`^\s*([\w.-]+)\s*(==|!=|=~|!~|<=|>=|=|<|>)\s*(.*?)\s*$`)
+
+// NewFilter returns a filter for the given tags of the form <key>=<value>, keys of absent tags, and conditions
+// of the form '<attribute> <operator> <value>'.
+func NewFilter(tags []string, tagsAbsent []string, conditions []string) (Filter, error) {
+	f := Filter{TagsAbsent: tagsAbsent}
+
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return Filter{}, fmt.Errorf("tag filter must be of form <key>=<value>: %s", tag)
+		}
+
+		if f.Tags == nil {
+			f.Tags = map[string]string{}
+		}
+		f.Tags[kv[0]] = kv[1]
+	}
+
+	for _, key := range tagsAbsent {
+		if key == "" {
+			return Filter{}, fmt.Errorf("key of absent tag filter must not be empty")
+		}
+	}
+
+	for _, c := range conditions {
+		condition, err := ParseCondition(c)
+		if err != nil {
+			return Filter{}, err
+		}
+
+		f.Conditions = append(f.Conditions, condition)
+	}
+
+	return f, nil
+}
+
+// ParseCondition parses a condition of the form '<attribute> <operator> <value>' (e.g., 'instance_type = t2.micro').
+// The value can be quoted.
+func ParseCondition(s string) (Condition, error) {
+	match := conditionRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return Condition{}, fmt.Errorf("condition must be of form '<attribute> <operator> <value>': %s", s)
+	}
+
+	c := Condition{
+		Attribute: match[1],
+		Operator:  match[2],
+		Value:     unquote(match[3]),
+	}
+
+	if c.Operator == "==" {
+		c.Operator = "="
+	}
+
+	if c.Operator == "=~" || c.Operator == "!~" {
+		re, err := regexp.Compile(c.Value)
+		if err != nil {
+			return Condition{}, fmt.Errorf("invalid regular expression in condition: %s: %s", s, err)
+		}
+		c.regexp = re
+	}
+
+	return c, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %s", c.Attribute, c.Operator, c.Value)
+}
+
+// IsEmpty returns true if the filter selects all resources.
+func (f Filter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.TagsAbsent) == 0 && len(f.Conditions) == 0 && !f.hasAgeLimit()
+}
+
+func (f Filter) hasAgeLimit() bool {
+	return f.OlderThan != 0 || f.NewerThan != 0
+}
+
+// Matches returns true if the Terraform state of the given resource matches the tags and conditions of the filter
+// (age limits are checked via MatchesAge).
+func (f Filter) Matches(r terraform.Resource) bool {
+	tags := StateTags(r)
+
+	for k, v := range f.Tags {
+		actual, ok := tags[k]
+		if !ok || actual != v {
+			return false
+		}
+	}
+
+	for _, k := range f.TagsAbsent {
+		if _, ok := tags[k]; ok {
+			return false
+		}
+	}
+
+	for _, c := range f.Conditions {
+		if !c.Matches(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches returns true if the Terraform state of the given resource satisfies the condition.
+// A condition on an attribute that doesn't exist (or is null) never matches. If the attribute is a list,
+// set, or map, = and =~ match if any element matches, and != and !~ match if no element matches.
+func (c Condition) Matches(r terraform.Resource) bool {
+	v, ok := stateAttribute(r.State, c.Attribute)
+	if !ok {
+		return false
+	}
+
+	ty := v.Type()
+	if ty.IsListType() || ty.IsSetType() || ty.IsMapType() || ty.IsTupleType() {
+		switch c.Operator {
+		case "=", "=~":
+			for it := v.ElementIterator(); it.Next(); {
+				_, e := it.Element()
+				if c.matchesValue(e) {
+					return true
+				}
+			}
+			return false
+		case "!=", "!~":
+			negated := c
+			negated.Operator = map[string]string{"!=": "=", "!~": "=~"}[c.Operator]
+
+			for it := v.ElementIterator(); it.Next(); {
+				_, e := it.Element()
+				if negated.matchesValue(e) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	return c.matchesValue(v)
+}
+
+func (c Condition) matchesValue(v cty.Value) bool {
+	if v.IsNull() || !v.IsWhollyKnown() {
+		return false
+	}
+
+	actual := formatAttribute(v)
+
+	switch c.Operator {
+	case "=":
+		return actual == c.Value
+	case "!=":
+		return actual != c.Value
+	case "=~":
+		return c.regexp.MatchString(actual)
+	case "!~":
+		return !c.regexp.MatchString(actual)
+	}
+
+	cmp := compare(actual, c.Value)
+
+	switch c.Operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+// compare compares two values numerically if both are numbers, otherwise lexically
+// (which also orders timestamps in RFC 3339 format correctly).
+func compare(a, b string) int {
+	aNum, errA := strconv.ParseFloat(a, 64)
+	bNum, errB := strconv.ParseFloat(b, 64)
+
+	if errA == nil && errB == nil {
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+// stateAttribute returns the value of the given (dot-separated) attribute path in the state.
+func stateAttribute(state *cty.Value, path string) (cty.Value, bool) {
+	if state == nil || state.IsNull() || !state.IsKnown() {
+		return cty.NilVal, false
+	}
+
+	v := *state
+
+	for _, name := range strings.Split(path, ".") {
+		if v.IsNull() || !v.IsKnown() {
+			return cty.NilVal, false
+		}
+
+		ty := v.Type()
+
+		switch {
+		case ty.IsObjectType():
+			if !ty.HasAttribute(name) {
+				return cty.NilVal, false
+			}
+			v = v.GetAttr(name)
+		case ty.IsMapType():
+			key := cty.StringVal(name)
+			if v.HasIndex(key).False() {
+				return cty.NilVal, false
+			}
+			v = v.Index(key)
+		case ty.IsListType() || ty.IsTupleType():
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= v.LengthInt() {
+				return cty.NilVal, false
+			}
+			v = v.Index(cty.NumberIntVal(int64(i)))
+		default:
+			return cty.NilVal, false
+		}
+	}
+
+	if v.IsNull() {
+		return cty.NilVal, false
+	}
+
+	return v, true
+}
+
+// StateTags returns the tags of the Terraform state of a resource,
+// or nil if the resource type doesn't support tags or has no state.
+func StateTags(r terraform.Resource) map[string]string {
+	v, ok := stateAttribute(r.State, "tags")
+	if !ok || !v.IsWhollyKnown() {
+		return nil
+	}
+
+	ty := v.Type()
+	if !ty.IsMapType() && !ty.IsObjectType() {
+		return nil
+	}
+
+	tags := map[string]string{}
+
+	for it := v.ElementIterator(); it.Next(); {
+		k, e := it.Element()
+		if e.IsNull() || e.Type() != cty.String {
+			continue
+		}
+		tags[k.AsString()] = e.AsString()
+	}
+
+	return tags
+}
+
+// MatchesAge returns true if the resource was created within the age limits of the filter at the given time.
+// The second return value is false if the creation time of the resource is unknown;
+// in that case, the resource only matches if the filter includes resources of unknown age.
+func (f Filter) MatchesAge(r terraform.Resource, now time.Time) (bool, bool) {
+	if !f.hasAgeLimit() {
+		return true, true
+	}
+
+	createdAt, ok := CreatedAt(r)
+	if !ok {
+		return f.IncludeUnknownAge, false
+	}
+
+	age := now.Sub(createdAt)
+
+	if f.OlderThan != 0 && age <= f.OlderThan {
+		return false, true
+	}
+
+	if f.NewerThan != 0 && age >= f.NewerThan {
+		return false, true
+	}
+
+	return true, true
+}
+
+// CreatedAt returns the creation time of a resource from a known timestamp attribute of its Terraform state,
+// or from the input (e.g., the CREATED column of awsls) if the state has none.
+func CreatedAt(r terraform.Resource) (time.Time, bool) {
+	for _, name := range timestampAttributes {
+		v, ok := stateAttribute(r.State, name)
+		if !ok || !v.IsKnown() || v.Type() != cty.String {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v.AsString())
+		if err != nil {
+			log.WithError(err).WithField("attribute", name).Debug("failed to parse timestamp")
+			continue
+		}
+
+		return t, true
+	}
+
+	if r.CreatedAt != nil {
+		return *r.CreatedAt, true
+	}
+
+	return time.Time{}, false
+}
+
+// ParseAge parses a duration like time.ParseDuration, but additionally accepts the units d (days) and w (weeks),
+// e.g. "3d" or "1w12h".
+func ParseAge(s string) (time.Duration, error) {
+	match := ageRegexp.FindStringSubmatch(s)
+	if match == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age: %s (examples: 72h, 3d, 1w)", s)
+		}
+		return d, nil
+	}
+
+	n, _ := strconv.Atoi(match[1])
+
+	d := time.Duration(n) * 24 * time.Hour
+	if match[2] == "w" {
+		d *= 7
+	}
+
+	if match[3] != "" {
+		rest, err := time.ParseDuration(match[3])
+		if err != nil {
+			return 0, fmt.Errorf("invalid age: %s (examples: 72h, 3d, 1w)", s)
+		}
+		d += rest
+	}
+
+	return d, nil
+}
+
+var ageRegexp = regexp.MustCompile(`^(\d+)([dw])(.*)$`)
+
+// ApplyFilter returns the resources that match the given filter.
+func ApplyFilter(resources []terraform.Resource, f Filter) []terraform.Resource {
+	return applyFilter(resources, f, time.Now())
+}
+
+func applyFilter(resources []terraform.Resource, f Filter, now time.Time) []terraform.Resource {
+	if f.IsEmpty() {
+		return resources
+	}
+
+	var result []terraform.Resource
+	var notMatching []terraform.Resource
+	var unknownAge []terraform.Resource
+
+	for _, r := range resources {
+		if !f.Matches(r) {
+			notMatching = append(notMatching, r)
+			skip(r, "not matching the filters (--tag, --tag-absent, --where)")
+			continue
+		}
+
+		matchesAge, knownAge := f.MatchesAge(r, now)
+		if !knownAge {
+			unknownAge = append(unknownAge, r)
+		}
+
+		if !matchesAge {
+			if knownAge {
+				notMatching = append(notMatching, r)
+				skip(r, f.ageMismatch(r, now))
+			}
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	if len(unknownAge) != 0 {
+		if f.IncludeUnknownAge {
+			internal.LogTitle("the following resources have no known creation time (not filtered by age)")
+		} else {
+			internal.LogTitle(fmt.Sprintf("skipping %d resource(s) without a known creation time "+
+				"(use --include-unknown-age to keep them)", len(unknownAge)))
+		}
+	}
+
+	for _, r := range unknownAge {
+		log.WithFields(IdentityFields(r)).Info(internal.Pad(r.Type))
+
+		if !f.IncludeUnknownAge {
+			skip(r, "unknown creation time")
+		}
+	}
+
+	if len(notMatching) != 0 {
+		internal.LogTitle(fmt.Sprintf("skipping %d resource(s) not matching the filters", len(notMatching)))
+	}
+
+	for _, r := range notMatching {
+		log.WithFields(IdentityFields(r)).Debug(internal.Pad(r.Type))
+	}
+
+	return result
+}
+
+// ageMismatch returns why a resource with known creation time doesn't match the age limits of the filter.
+func (f Filter) ageMismatch(r terraform.Resource, now time.Time) string {
+	createdAt, _ := CreatedAt(r)
+
+	if f.OlderThan != 0 && now.Sub(createdAt) <= f.OlderThan {
+		return fmt.Sprintf("not older than %s (--older-than)", f.OlderThan)
+	}
+
+	return fmt.Sprintf("not newer than %s (--newer-than)", f.NewerThan)
+}
+
+// skip emits an event that the resource is skipped for the given reason.
+func skip(r terraform.Resource, reason string) {
+	e := newEvent(EventSkipped, r)
+	e.Reason = reason
+	emitEvent(e)
+}
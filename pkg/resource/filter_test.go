@@ -0,0 +1,316 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Condition
+		expectedErr string
+	}{
+		{
+			name:     "with spaces",
+			input:    "instance_type = t2.micro",
+			expected: Condition{Attribute: "instance_type", Operator: "=", Value: "t2.micro"},
+		},
+		{
+			name:     "without spaces",
+			input:    "instance_type!=t2.micro",
+			expected: Condition{Attribute: "instance_type", Operator: "!=", Value: "t2.micro"},
+		},
+		{
+			name:     "double equals",
+			input:    "instance_type == t2.micro",
+			expected: Condition{Attribute: "instance_type", Operator: "=", Value: "t2.micro"},
+		},
+		{
+			name:     "quoted value with spaces",
+			input:    `tags.Name = "my instance"`,
+			expected: Condition{Attribute: "tags.Name", Operator: "=", Value: "my instance"},
+		},
+		{
+			name:        "missing operator",
+			input:       "instance_type t2.micro",
+			expectedErr: "condition must be of form '<attribute> <operator> <value>': instance_type t2.micro",
+		},
+		{
+			name:        "invalid regex",
+			input:       "instance_type =~ (",
+			expectedErr: "invalid regular expression in condition: instance_type =~ (: error parsing regexp: missing closing ): `(`",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseCondition(tc.input)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	state := cty.ObjectVal(map[string]cty.Value{
+		"id":             cty.StringVal("i-1"),
+		"instance_type":  cty.StringVal("m5.large"),
+		"cpu_core_count": cty.NumberIntVal(2),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"Name": cty.StringVal("my instance"),
+			"env":  cty.StringVal("test"),
+		}),
+		"security_groups": cty.SetVal([]cty.Value{cty.StringVal("sg-1"), cty.StringVal("sg-2")}),
+		"root_block_device": cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"volume_size": cty.NumberIntVal(100),
+		})}),
+		"key_name": cty.NullVal(cty.String),
+	})
+
+	r := terraform.Resource{Type: "aws_instance", ID: "i-1", State: &state}
+
+	tests := []struct {
+		name       string
+		tags       []string
+		tagsAbsent []string
+		conditions []string
+		expected   bool
+	}{
+		{name: "empty filter", expected: true},
+		{name: "tag", tags: []string{"env=test"}, expected: true},
+		{name: "tag with space", tags: []string{"Name=my instance"}, expected: true},
+		{name: "tag with other value", tags: []string{"env=prod"}, expected: false},
+		{name: "tag missing", tags: []string{"owner=me"}, expected: false},
+		{name: "tag absent", tagsAbsent: []string{"owner"}, expected: true},
+		{name: "tag not absent", tagsAbsent: []string{"env"}, expected: false},
+		{name: "equals", conditions: []string{"instance_type = m5.large"}, expected: true},
+		{name: "not equals", conditions: []string{"instance_type != m5.large"}, expected: false},
+		{name: "regex", conditions: []string{"instance_type =~ ^m5"}, expected: true},
+		{name: "negated regex", conditions: []string{"instance_type !~ ^m5"}, expected: false},
+		{name: "number comparison", conditions: []string{"cpu_core_count < 10"}, expected: true},
+		{name: "nested attribute", conditions: []string{"root_block_device.0.volume_size >= 100"}, expected: true},
+		{name: "map element", conditions: []string{"tags.env = test"}, expected: true},
+		{name: "set contains", conditions: []string{"security_groups = sg-2"}, expected: true},
+		{name: "set doesn't contain", conditions: []string{"security_groups != sg-2"}, expected: false},
+		{name: "unknown attribute", conditions: []string{"foo != bar"}, expected: false},
+		{name: "null attribute", conditions: []string{"key_name != foo"}, expected: false},
+		{
+			name:       "all must match",
+			tags:       []string{"env=test"},
+			conditions: []string{"instance_type = m5.large", "cpu_core_count > 2"},
+			expected:   false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewFilter(tc.tags, tc.tagsAbsent, tc.conditions)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, f.Matches(r))
+		})
+	}
+}
+
+func TestNewFilter_InvalidTag(t *testing.T) {
+	_, err := NewFilter([]string{"env"}, nil, nil)
+	assert.EqualError(t, err, "tag filter must be of form <key>=<value>: env")
+}
+
+func TestFilter_MatchesAge(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	withLaunchTime := func(launchTime string) terraform.Resource {
+		state := cty.ObjectVal(map[string]cty.Value{
+			"id":          cty.StringVal("i-1"),
+			"launch_time": cty.StringVal(launchTime),
+		})
+		return terraform.Resource{Type: "aws_instance", ID: "i-1", State: &state}
+	}
+
+	noTimestampState := cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("vpc-1")})
+	createdAt := now.Add(-5 * 24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		filter        Filter
+		r             terraform.Resource
+		expected      bool
+		expectedKnown bool
+	}{
+		{
+			name:          "older than",
+			filter:        Filter{OlderThan: 72 * time.Hour},
+			r:             withLaunchTime("2021-06-01T12:00:00Z"),
+			expected:      true,
+			expectedKnown: true,
+		},
+		{
+			name:          "not older than",
+			filter:        Filter{OlderThan: 72 * time.Hour},
+			r:             withLaunchTime("2021-06-09T12:00:00Z"),
+			expected:      false,
+			expectedKnown: true,
+		},
+		{
+			name:          "newer than",
+			filter:        Filter{NewerThan: 72 * time.Hour},
+			r:             withLaunchTime("2021-06-09T12:00:00.000Z"),
+			expected:      true,
+			expectedKnown: true,
+		},
+		{
+			name:          "between",
+			filter:        Filter{OlderThan: 24 * time.Hour, NewerThan: 72 * time.Hour},
+			r:             withLaunchTime("2021-06-01T12:00:00Z"),
+			expected:      false,
+			expectedKnown: true,
+		},
+		{
+			name:          "created at from input",
+			filter:        Filter{OlderThan: 72 * time.Hour},
+			r:             terraform.Resource{Type: "aws_vpc", ID: "vpc-1", State: &noTimestampState, CreatedAt: &createdAt},
+			expected:      true,
+			expectedKnown: true,
+		},
+		{
+			name:          "unknown creation time",
+			filter:        Filter{OlderThan: 72 * time.Hour},
+			r:             terraform.Resource{Type: "aws_vpc", ID: "vpc-1", State: &noTimestampState},
+			expected:      false,
+			expectedKnown: false,
+		},
+		{
+			name:          "unknown creation time included",
+			filter:        Filter{OlderThan: 72 * time.Hour, IncludeUnknownAge: true},
+			r:             terraform.Resource{Type: "aws_vpc", ID: "vpc-1", State: &noTimestampState},
+			expected:      true,
+			expectedKnown: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, actualKnown := tc.filter.MatchesAge(tc.r, now)
+
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expectedKnown, actualKnown)
+		})
+	}
+}
+
+func TestApplyFilter_SkippedEvents(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	withLaunchTime := func(id, launchTime string) terraform.Resource {
+		state := cty.ObjectVal(map[string]cty.Value{
+			"id":          cty.StringVal(id),
+			"launch_time": cty.StringVal(launchTime),
+			"tags":        cty.MapVal(map[string]cty.Value{"env": cty.StringVal("test")}),
+		})
+		return terraform.Resource{Type: "aws_instance", ID: id, Region: "us-east-1", State: &state}
+	}
+
+	noTimestampState := cty.ObjectVal(map[string]cty.Value{
+		"id":   cty.StringVal("vpc-1"),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("test")}),
+	})
+
+	old := withLaunchTime("i-1", "2021-06-01T12:00:00Z")
+	recent := withLaunchTime("i-2", "2021-06-09T12:00:00Z")
+	unknownAge := terraform.Resource{Type: "aws_vpc", ID: "vpc-1", Region: "us-east-1", State: &noTimestampState}
+
+	otherTagState := cty.ObjectVal(map[string]cty.Value{
+		"id":   cty.StringVal("vpc-2"),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+	})
+	otherTag := terraform.Resource{Type: "aws_vpc", ID: "vpc-2", Region: "us-east-1", State: &otherTagState}
+
+	resources := []terraform.Resource{old, recent, unknownAge, otherTag}
+
+	tests := []struct {
+		name            string
+		filter          Filter
+		expected        []terraform.Resource
+		expectedSkipped map[string]string
+	}{
+		{
+			name:     "older than",
+			filter:   Filter{Tags: map[string]string{"env": "test"}, OlderThan: 72 * time.Hour},
+			expected: []terraform.Resource{old},
+			expectedSkipped: map[string]string{
+				"i-2":   "not older than 72h0m0s (--older-than)",
+				"vpc-1": "unknown creation time",
+				"vpc-2": "not matching the filters (--tag, --tag-absent, --where)",
+			},
+		},
+		{
+			name:     "newer than including unknown age",
+			filter:   Filter{NewerThan: 72 * time.Hour, IncludeUnknownAge: true},
+			expected: []terraform.Resource{recent, unknownAge, otherTag},
+			expectedSkipped: map[string]string{
+				"i-1": "not newer than 72h0m0s (--newer-than)",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			SetEventOutput(&buf, EventsJSON)
+			defer SetEventOutput(nil, "")
+
+			assert.Equal(t, tc.expected, applyFilter(resources, tc.filter, now))
+
+			require.NoError(t, FlushEvents())
+
+			var events []Event
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &events))
+
+			actualSkipped := map[string]string{}
+			for _, e := range events {
+				assert.Equal(t, EventSkipped, e.Event)
+				actualSkipped[e.ID] = e.Reason
+			}
+
+			assert.Equal(t, tc.expectedSkipped, actualSkipped)
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    time.Duration
+		expectedErr string
+	}{
+		{input: "72h", expected: 72 * time.Hour},
+		{input: "3d", expected: 72 * time.Hour},
+		{input: "1w12h", expected: 7*24*time.Hour + 12*time.Hour},
+		{input: "3days", expectedErr: "invalid age: 3days (examples: 72h, 3d, 1w)"},
+		{input: "foo", expectedErr: "invalid age: foo (examples: 72h, 3d, 1w)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := ParseAge(tc.input)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// referenceAttributes are names of attributes that reference other resources, besides the ones with a suffix
+// of referenceAttributeSuffixes (e.g., vpc_id, subnet_ids, role_arn).
+var referenceAttributes = map[string]bool{
+	"cluster":              true,
+	"group":                true,
+	"groups":               true,
+	"iam_instance_profile": true,
+	"instance":             true,
+	"launch_configuration": true,
+	"launch_template":      true,
+	"load_balancers":       true,
+	"policy":               true,
+	"role":                 true,
+	"roles":                true,
+	"security_groups":      true,
+	"service_role":         true,
+	"subnets":              true,
+	"target_group_arns":    true,
+	"user":                 true,
+	"users":                true,
+	"vpc_zone_identifier":  true,
+}
+
+var referenceAttributeSuffixes = []string{"_id", "_ids", "_arn", "_arns", "_name", "_names"}
+
+// nonReferenceAttributes identify a resource itself or its owner, instead of referencing another resource.
+// In a nested block of a reference attribute (e.g., iam_instance_profile { arn = ... }), they are references, too.
+var nonReferenceAttributes = map[string]bool{
+	"id":       true,
+	"arn":      true,
+	"name":     true,
+	"owner_id": true,
+}
+
+func isReferenceAttribute(name string) bool {
+	if nonReferenceAttributes[name] {
+		return false
+	}
+
+	if referenceAttributes[name] {
+		return true
+	}
+
+	for _, suffix := range referenceAttributeSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dependencyGraph describes which of the given resources reference (i.e., depend on) each other, based on
+// reference attributes in their Terraform states (e.g., a subnet references its VPC via the attribute vpc_id).
+type dependencyGraph struct {
+	resources []terraform.Resource
+	// dependencies are the indices of the resources each resource references
+	dependencies [][]int
+	// dependents are the indices of the resources that reference each resource
+	dependents [][]int
+}
+
+// newDependencyGraph returns the dependency graph of the given resources. The state of the resources must have been
+// fetched before. A resource references another one if the value of one of its reference attributes is the ID, ARN,
+// or name of the other resource in the same profile.
+func newDependencyGraph(resources []terraform.Resource) *dependencyGraph {
+	g := &dependencyGraph{
+		resources:    resources,
+		dependencies: make([][]int, len(resources)),
+		dependents:   make([][]int, len(resources)),
+	}
+
+	type key struct {
+		profile, value string
+	}
+
+	index := map[key][]int{}
+
+	for i, r := range resources {
+		values := []string{r.ID}
+		for _, name := range []string{"arn", "name"} {
+			v, ok := stateAttribute(r.State, name)
+			if ok && v.IsKnown() && v.Type() == cty.String && v.AsString() != "" {
+				values = append(values, v.AsString())
+			}
+		}
+
+		for _, v := range values {
+			k := key{r.Profile, v}
+			index[k] = append(index[k], i)
+		}
+	}
+
+	for i, r := range resources {
+		if r.State == nil {
+			continue
+		}
+
+		seen := map[int]bool{}
+
+		for _, ref := range collectReferences(*r.State, "") {
+			for _, j := range index[key{r.Profile, ref}] {
+				// ignore references to itself (e.g., a security group rule allowing traffic from the same group)
+				if j == i || seen[j] {
+					continue
+				}
+				seen[j] = true
+
+				g.dependencies[i] = append(g.dependencies[i], j)
+				g.dependents[j] = append(g.dependents[j], i)
+			}
+		}
+	}
+
+	return g
+}
+
+// collectReferences returns the string values of all reference attributes in the given value,
+// including the ones of nested blocks (e.g., the routes of a route table).
+func collectReferences(v cty.Value, name string) []string {
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+
+	ty := v.Type()
+
+	switch {
+	case ty == cty.String:
+		if isReferenceAttribute(name) && v.AsString() != "" {
+			return []string{v.AsString()}
+		}
+	case ty.IsObjectType():
+		var result []string
+
+		for attrName := range ty.AttributeTypes() {
+			// tags reference nothing, but their values could accidentally match the ID of another resource
+			if attrName == "tags" || attrName == "tags_all" {
+				continue
+			}
+
+			// the ID, ARN, or name in a block of a reference attribute is the reference (e.g., launch_template { id })
+			if isReferenceAttribute(name) && nonReferenceAttributes[attrName] {
+				result = append(result, collectReferences(v.GetAttr(attrName), name)...)
+				continue
+			}
+
+			result = append(result, collectReferences(v.GetAttr(attrName), attrName)...)
+		}
+
+		return result
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		var result []string
+
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			result = append(result, collectReferences(e, name)...)
+		}
+
+		return result
+	}
+
+	return nil
+}
+
+// deletionLayers returns the resources in the order of deletion, where resources that are referenced by others are
+// deleted after them. The resources of each layer don't depend on each other and can be deleted in parallel.
+//
+// Resources that depend on each other in a cycle are deleted together in the same layer;
+// the cycles are returned as well.
+func (g *dependencyGraph) deletionLayers() ([][]terraform.Resource, [][]terraform.Resource) {
+	layers, cycles := g.deletionLayerIndices()
+
+	var layerResources [][]terraform.Resource
+	for _, layer := range layers {
+		layerResources = append(layerResources, g.sortedResources(layer))
+	}
+
+	var cycleResources [][]terraform.Resource
+	for _, cycle := range cycles {
+		cycleResources = append(cycleResources, g.sortedResources(cycle))
+	}
+
+	return layerResources, cycleResources
+}
+
+// deletionLayerIndices is the same as deletionLayers, but returns the indices of the resources (in ascending order).
+func (g *dependencyGraph) deletionLayerIndices() ([][]int, [][]int) {
+	components := g.stronglyConnectedComponents()
+
+	componentOf := make([]int, len(g.resources))
+	for c, members := range components {
+		for _, i := range members {
+			componentOf[i] = c
+		}
+	}
+
+	// the number of other components that reference each component and haven't been deleted yet
+	numDependents := make([]int, len(components))
+	// the components each component references
+	dependencies := make([]map[int]bool, len(components))
+
+	for c := range components {
+		dependencies[c] = map[int]bool{}
+	}
+
+	for i, deps := range g.dependencies {
+		for _, j := range deps {
+			from, to := componentOf[i], componentOf[j]
+			if from == to || dependencies[from][to] {
+				continue
+			}
+
+			dependencies[from][to] = true
+			numDependents[to]++
+		}
+	}
+
+	var next []int
+	for c := range components {
+		if numDependents[c] == 0 {
+			next = append(next, c)
+		}
+	}
+
+	var layers [][]int
+
+	for len(next) > 0 {
+		var layer []int
+		var following []int
+
+		for _, c := range next {
+			layer = append(layer, components[c]...)
+
+			for dep := range dependencies[c] {
+				numDependents[dep]--
+				if numDependents[dep] == 0 {
+					following = append(following, dep)
+				}
+			}
+		}
+
+		layers = append(layers, sortedIndices(layer))
+		next = following
+	}
+
+	var cycles [][]int
+	for _, members := range components {
+		if len(members) > 1 {
+			cycles = append(cycles, sortedIndices(members))
+		}
+	}
+
+	return layers, cycles
+}
+
+// sortedResources returns the resources of the given indices in the order of the input.
+func (g *dependencyGraph) sortedResources(indices []int) []terraform.Resource {
+	var result []terraform.Resource
+	for _, i := range sortedIndices(indices) {
+		result = append(result, g.resources[i])
+	}
+
+	return result
+}
+
+func sortedIndices(indices []int) []int {
+	sorted := make([]int, len(indices))
+	copy(sorted, indices)
+	sort.Ints(sorted)
+
+	return sorted
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the graph (via Tarjan's algorithm),
+// i.e. resources that reference each other in a cycle are members of the same component.
+func (g *dependencyGraph) stronglyConnectedComponents() [][]int {
+	index := 0
+	indices := make([]int, len(g.resources))
+	lowLinks := make([]int, len(g.resources))
+	onStack := make([]bool, len(g.resources))
+	visited := make([]bool, len(g.resources))
+
+	var stack []int
+	var components [][]int
+
+	var visit func(i int)
+	visit = func(i int) {
+		indices[i] = index
+		lowLinks[i] = index
+		index++
+		visited[i] = true
+
+		stack = append(stack, i)
+		onStack[i] = true
+
+		for _, j := range g.dependencies[i] {
+			if !visited[j] {
+				visit(j)
+				if lowLinks[j] < lowLinks[i] {
+					lowLinks[i] = lowLinks[j]
+				}
+			} else if onStack[j] && indices[j] < lowLinks[i] {
+				lowLinks[i] = indices[j]
+			}
+		}
+
+		if lowLinks[i] == indices[i] {
+			var component []int
+
+			for {
+				j := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[j] = false
+
+				component = append(component, j)
+
+				if j == i {
+					break
+				}
+			}
+
+			components = append(components, component)
+		}
+	}
+
+	for i := range g.resources {
+		if !visited[i] {
+			visit(i)
+		}
+	}
+
+	return components
+}
+
+// treeNode is a resource in the tree of dependents, where depth is the number of its ancestors.
+type treeNode struct {
+	resource terraform.Resource
+	depth    int
+}
+
+// tree returns the resources in depth-first order of a tree, where each resource is a child of a resource it depends
+// on. The roots are the resources that don't depend on any other of the resources. A resource that depends on multiple
+// resources is only listed once, below the first one.
+func (g *dependencyGraph) tree() []treeNode {
+	var result []treeNode
+
+	visited := make([]bool, len(g.resources))
+	all := func(int) bool { return true }
+
+	for i := range g.resources {
+		if len(g.dependencies[i]) == 0 && !visited[i] {
+			result = append(result, g.subtree(i, all, visited)...)
+		}
+	}
+
+	// resources in a cycle without any root
+	for i := range g.resources {
+		if !visited[i] {
+			result = append(result, g.subtree(i, all, visited)...)
+		}
+	}
+
+	return result
+}
+
+// subtree returns the resource of the given index and, in depth-first order, all resources that depend on it
+// (directly or transitively) and are included. Resources already visited are skipped.
+func (g *dependencyGraph) subtree(root int, include func(i int) bool, visited []bool) []treeNode {
+	var result []treeNode
+
+	var visit func(i, depth int)
+	visit = func(i, depth int) {
+		visited[i] = true
+		result = append(result, treeNode{g.resources[i], depth})
+
+		for _, j := range sortedIndices(g.dependents[i]) {
+			if !visited[j] && include(j) {
+				visit(j, depth+1)
+			}
+		}
+	}
+
+	visit(root, 0)
+
+	return result
+}
+
+// formatTreeNode returns the resource type of a tree node, indented by its depth.
+func formatTreeNode(n treeNode) string {
+	if n.depth == 0 {
+		return n.resource.Type
+	}
+
+	return strings.Repeat("   ", n.depth-1) + "└─ " + n.resource.Type
+}
+
+// formatCycle returns a description of resources that depend on each other in a cycle.
+func formatCycle(resources []terraform.Resource) string {
+	var list []string
+	for _, r := range resources {
+		list = append(list, fmt.Sprintf("%s %s", r.Type, r.ID))
+	}
+
+	return strings.Join(list, ", ")
+}
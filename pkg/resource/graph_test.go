@@ -0,0 +1,123 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func withState(rType, id string, attrs map[string]cty.Value) terraform.Resource {
+	attrs["id"] = cty.StringVal(id)
+	state := cty.ObjectVal(attrs)
+
+	return terraform.Resource{Type: rType, ID: id, Profile: "myaccount", Region: "us-west-2", State: &state}
+}
+
+func ids(layers [][]terraform.Resource) [][]string {
+	var result [][]string
+
+	for _, layer := range layers {
+		var layerIDs []string
+		for _, r := range layer {
+			layerIDs = append(layerIDs, r.ID)
+		}
+		result = append(result, layerIDs)
+	}
+
+	return result
+}
+
+func TestDependencyGraph_DeletionLayers(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{
+		"cidr_block": cty.StringVal("10.0.0.0/16"),
+	})
+	subnet := withState("aws_subnet", "subnet-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+		// a rule referencing its own group
+		"ingress": cty.SetVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"security_groups": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+		})}),
+	})
+	role := withState("aws_iam_role", "my-role", map[string]cty.Value{
+		"arn":  cty.StringVal("arn:aws:iam::123456789012:role/my-role"),
+		"name": cty.StringVal("my-role"),
+		"tags": cty.MapVal(map[string]cty.Value{"vpc_id": cty.StringVal("vpc-1")}),
+	})
+	instanceProfile := withState("aws_iam_instance_profile", "my-profile", map[string]cty.Value{
+		"role": cty.StringVal("my-role"),
+	})
+	instance := withState("aws_instance", "i-1", map[string]cty.Value{
+		"subnet_id":              cty.StringVal("subnet-1"),
+		"vpc_security_group_ids": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+		"iam_instance_profile":   cty.StringVal("my-profile"),
+		"ami":                    cty.StringVal("ami-1"),
+	})
+
+	resources := []terraform.Resource{vpc, subnet, securityGroup, role, instanceProfile, instance}
+
+	layers, cycles := newDependencyGraph(resources).deletionLayers()
+
+	assert.Empty(t, cycles)
+	assert.Equal(t, [][]string{
+		{"i-1"},
+		{"subnet-1", "sg-1", "my-profile"},
+		{"vpc-1", "my-role"},
+	}, ids(layers))
+}
+
+func TestDependencyGraph_DeletionLayers_Cycle(t *testing.T) {
+	a := withState("aws_security_group", "sg-a", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+		"ingress": cty.SetVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"security_groups": cty.SetVal([]cty.Value{cty.StringVal("sg-b")}),
+		})}),
+	})
+	b := withState("aws_security_group", "sg-b", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+		"ingress": cty.SetVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+			"security_groups": cty.SetVal([]cty.Value{cty.StringVal("sg-a")}),
+		})}),
+	})
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+
+	// the same ID in another profile is a different resource
+	otherVpc := vpc
+	otherVpc.Profile = "otheraccount"
+
+	layers, cycles := newDependencyGraph([]terraform.Resource{vpc, a, b, otherVpc}).deletionLayers()
+
+	assert.Equal(t, [][]string{{"sg-a", "sg-b", "vpc-1"}, {"vpc-1"}}, ids(layers))
+	assert.Equal(t, [][]string{{"sg-a", "sg-b"}}, ids(cycles))
+	assert.Equal(t, "aws_security_group sg-a, aws_security_group sg-b", formatCycle(cycles[0]))
+}
+
+func TestDependencyGraph_Tree(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	subnet := withState("aws_subnet", "subnet-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+	securityGroup := withState("aws_security_group", "sg-1", map[string]cty.Value{
+		"vpc_id": cty.StringVal("vpc-1"),
+	})
+	instance := withState("aws_instance", "i-1", map[string]cty.Value{
+		"subnet_id":              cty.StringVal("subnet-1"),
+		"vpc_security_group_ids": cty.SetVal([]cty.Value{cty.StringVal("sg-1")}),
+	})
+
+	var actual []string
+	for _, n := range newDependencyGraph([]terraform.Resource{instance, securityGroup, subnet, vpc}).tree() {
+		actual = append(actual, formatTreeNode(n)+" "+n.resource.ID)
+	}
+
+	assert.Equal(t, []string{
+		"aws_vpc vpc-1",
+		"└─ aws_security_group sg-1",
+		"   └─ aws_instance i-1",
+		"└─ aws_subnet subnet-1",
+	}, actual)
+}
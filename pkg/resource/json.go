@@ -0,0 +1,181 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// jsonResource is the JSON representation of a resource accepted as input.
+type jsonResource struct {
+	Type       string                             `json:"type"`
+	ID         string                             `json:"id"`
+	Profile    string                             `json:"profile"`
+	Region     string                             `json:"region"`
+	AccountID  string                             `json:"account_id"`
+	Attributes map[string]ctyjson.SimpleJSONValue `json:"attributes"`
+}
+
+// isJSON returns true if the given input starts with a JSON object or array.
+func isJSON(input []byte) bool {
+	input = bytes.TrimSpace(input)
+
+	return len(input) > 0 && (input[0] == '{' || input[0] == '[')
+}
+
+// readJSON reads resources either from top-level JSON arrays of objects or from one JSON object per line.
+// Invalid objects are returned as LineErrors together with all valid resources.
+func readJSON(input []byte) ([]terraform.Resource, error) {
+	if bytes.TrimSpace(input)[0] == '[' {
+		return readJSONArray(input)
+	}
+
+	var result []terraform.Resource
+	var lineErrs LineErrors
+
+	for i, line := range strings.Split(string(input), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		r, err := readJSONObject([]byte(line))
+		if err != nil {
+			lineErrs = append(lineErrs, LineError{Line: i + 1, Text: line, Err: err})
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	if len(lineErrs) > 0 {
+		return result, lineErrs
+	}
+
+	return result, nil
+}
+
+// readJSONArray reads resources from one or more top-level JSON arrays of objects (e.g., awsls prints an array
+// per resource type). Errors refer to the line where an invalid object begins.
+//
+// Invalid objects are returned as LineErrors together with all valid resources. Invalid JSON (e.g., a syntax error
+// or trailing data that isn't an array) is returned as an error without any resources, as the remaining input
+// can't be read after it.
+func readJSONArray(input []byte) ([]terraform.Resource, error) {
+	var result []terraform.Resource
+	var lineErrs LineErrors
+
+	dec := json.NewDecoder(bytes.NewReader(input))
+
+	for {
+		line := lineAt(input, int(dec.InputOffset()))
+
+		// opening bracket of an array
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %s", line, err)
+		}
+
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("line %d: invalid JSON: expected an array of objects, found: %v", line, token)
+		}
+
+		for dec.More() {
+			line := lineAt(input, int(dec.InputOffset()))
+
+			var raw json.RawMessage
+
+			err := dec.Decode(&raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid JSON: %s", line, err)
+			}
+
+			r, err := readJSONObject(raw)
+			if err != nil {
+				var compact bytes.Buffer
+				_ = json.Compact(&compact, raw)
+
+				lineErrs = append(lineErrs, LineError{Line: line, Text: compact.String(), Err: err})
+
+				continue
+			}
+
+			result = append(result, r)
+		}
+
+		// closing bracket of the array
+		_, err = dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %s", lineAt(input, int(dec.InputOffset())), err)
+		}
+	}
+
+	if len(lineErrs) > 0 {
+		return result, lineErrs
+	}
+
+	return result, nil
+}
+
+// lineAt returns the line number of the first JSON value found at or after the given offset.
+func lineAt(input []byte, offset int) int {
+	for offset < len(input) && strings.ContainsRune(" \t\r\n,", rune(input[offset])) {
+		offset++
+	}
+
+	return bytes.Count(input[:offset], []byte("\n")) + 1
+}
+
+func readJSONObject(data []byte) (terraform.Resource, error) {
+	var jr jsonResource
+
+	err := json.Unmarshal(data, &jr)
+	if err != nil {
+		return terraform.Resource{}, fmt.Errorf("invalid JSON: %s", err)
+	}
+
+	return jr.toResource()
+}
+
+func (jr jsonResource) toResource() (terraform.Resource, error) {
+	if jr.Type == "" || jr.ID == "" {
+		return terraform.Resource{}, fmt.Errorf("fields 'type' and 'id' are required")
+	}
+
+	rType := PrefixResourceType(jr.Type)
+	if !terraform.IsType(rType) {
+		return terraform.Resource{}, fmt.Errorf("no resource type found: %s", rType)
+	}
+
+	profile := jr.Profile
+	if profile == `N/A` {
+		profile = ""
+	}
+
+	var attrs map[string]cty.Value
+	if len(jr.Attributes) > 0 {
+		attrs = make(map[string]cty.Value, len(jr.Attributes))
+
+		for k, v := range jr.Attributes {
+			attrs[k] = v.Value
+		}
+	}
+
+	return terraform.Resource{
+		Type:      rType,
+		ID:        jr.ID,
+		Profile:   profile,
+		Region:    jr.Region,
+		AccountID: jr.AccountID,
+		Attrs:     attrs,
+	}, nil
+}
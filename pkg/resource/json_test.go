@@ -0,0 +1,126 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRead_JSON(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		expectedResources []terraform.Resource
+		expectedErr       string
+	}{
+		{
+			name: "one object per line",
+			input: `{"type": "vpc", "id": "vpc-1", "profile": "myaccount", "region": "us-east-1"}
+{"type": "aws_iam_role", "id": "my role", "region": "us-west-2", "account_id": "123456789012"}
+`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+				{Type: "aws_iam_role", ID: "my role", Region: "us-west-2", AccountID: "123456789012"},
+			},
+		},
+		{
+			name: "top-level array",
+			input: `[
+  {"type": "aws_vpc", "id": "vpc-1", "profile": "N/A", "region": "us-east-1"},
+  {"type": "aws_vpc", "id": "vpc-2", "profile": "myaccount", "region": "us-east-1"}
+]`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1", Region: "us-east-1"},
+				{Type: "aws_vpc", ID: "vpc-2", Profile: "myaccount", Region: "us-east-1"},
+			},
+		},
+		{
+			name: "with attributes",
+			input: `{"type": "aws_iam_role_policy_attachment", "id": "foo-123", "region": "us-east-1", ` +
+				`"attributes": {"role": "foo", "policy_arn": "arn:aws:iam::aws:policy/ReadOnlyAccess"}}`,
+			expectedResources: []terraform.Resource{
+				{
+					Type:   "aws_iam_role_policy_attachment",
+					ID:     "foo-123",
+					Region: "us-east-1",
+					Attrs: map[string]cty.Value{
+						"role":       cty.StringVal("foo"),
+						"policy_arn": cty.StringVal("arn:aws:iam::aws:policy/ReadOnlyAccess"),
+					},
+				},
+			},
+		},
+		{
+			name:        "missing id",
+			input:       `{"type": "aws_vpc", "region": "us-east-1"}`,
+			expectedErr: "line 1: fields 'type' and 'id' are required",
+		},
+		{
+			name: "unknown resource type in array",
+			input: `[
+  {"type": "aws_vpc", "id": "vpc-1"},
+  {"type": "foo", "id": "bar"}
+]`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1"},
+			},
+			expectedErr: `line 3: no resource type found: aws_foo: {"type":"foo","id":"bar"}`,
+		},
+		{
+			name: "multiple arrays",
+			input: `[
+  {"type": "aws_vpc", "id": "vpc-1", "region": "us-east-1"}
+]
+[
+  {"type": "aws_iam_role", "id": "my-role", "region": "us-east-1"}
+]
+`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1", Region: "us-east-1"},
+				{Type: "aws_iam_role", ID: "my-role", Region: "us-east-1"},
+			},
+		},
+		{
+			name: "syntax error in array",
+			input: `[
+  {"type": "aws_vpc", "id": "vpc-1"},
+  {"type": "aws_vpc", "id": },
+  {"type": "aws_vpc", "id": "vpc-3"}
+]`,
+			expectedErr: "line 3: invalid JSON: invalid character '}'",
+		},
+		{
+			name: "trailing data after array",
+			input: `[{"type": "aws_vpc", "id": "vpc-1"}]
+{"type": "aws_vpc", "id": "vpc-2"}`,
+			expectedErr: "line 2: invalid JSON: expected an array of objects, found: {",
+		},
+		{
+			name: "malformed lines",
+			input: `{"type": "aws_vpc", "id": 
+{"type": "aws_vpc", "id": "vpc-1"}
+{"type": "aws_vpc", "id": "vpc-2"}}`,
+			expectedResources: []terraform.Resource{
+				{Type: "aws_vpc", ID: "vpc-1"},
+			},
+			expectedErr: "line 1: invalid JSON: unexpected end of JSON input",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualResources, _, err := Read(strings.NewReader(tc.input))
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedResources, actualResources)
+		})
+	}
+}
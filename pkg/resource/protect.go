@@ -0,0 +1,292 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v2"
+)
+
+// Protection describes resources that must never be deleted, even if deletion is forced.
+type Protection struct {
+	ids      []pattern
+	names    []pattern
+	tags     []tagSelector
+	accounts map[string]bool
+	// protectionTags are added to the default protection tags; unlike the tags above,
+	// they can be ignored on request via ApplyProtectionTags
+	protectionTags []tagSelector
+}
+
+// defaultProtectionTags are tags that are commonly used by (janitor) tools to mark resources that must not be deleted.
+var defaultProtectionTags = []string{
+	"awsrm:protect=true",
+	"DoNotDelete",
+}
+
+// protectionConfig is the format of a protection file, for example:
+//
+// 	ids:
+// 	  - vpc-0a1b2c3d
+// 	  - nat-*
+// 	names:
+// 	  - shared-*
+// 	tags:
+// 	  - env=prod
+// 	  - DoNotDelete
+// 	accounts:
+// 	  - "123456789012"
+// 	protection_tags:
+// 	  - keep=yes
+type protectionConfig struct {
+	IDs            []string `yaml:"ids"`
+	Names          []string `yaml:"names"`
+	Tags           []string `yaml:"tags"`
+	Accounts       []string `yaml:"accounts"`
+	ProtectionTags []string `yaml:"protection_tags"`
+}
+
+// ProtectedResource is a resource that is skipped for deletion, because it is protected.
+type ProtectedResource struct {
+	terraform.Resource
+	// Reason describes which rule protects the resource (e.g., "id matches nat-*").
+	Reason string
+}
+
+// pattern is a glob, where * matches any sequence of characters and ? matches any single character.
+type pattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+type tagSelector struct {
+	key   string
+	value *pattern
+}
+
+// ReadProtection reads the protection rules in YAML format from the given reader.
+func ReadProtection(r io.Reader) (Protection, error) {
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Protection{}, err
+	}
+
+	var config protectionConfig
+
+	err = yaml.UnmarshalStrict(input, &config)
+	if err != nil {
+		return Protection{}, err
+	}
+
+	p := Protection{accounts: map[string]bool{}}
+
+	for _, id := range config.IDs {
+		p.ids = append(p.ids, newPattern(id))
+	}
+
+	for _, name := range config.Names {
+		p.names = append(p.names, newPattern(name))
+	}
+
+	for _, tag := range config.Tags {
+		selector, err := newTagSelector(tag)
+		if err != nil {
+			return Protection{}, err
+		}
+
+		p.tags = append(p.tags, selector)
+	}
+
+	for _, tag := range config.ProtectionTags {
+		selector, err := newTagSelector(tag)
+		if err != nil {
+			return Protection{}, err
+		}
+
+		p.protectionTags = append(p.protectionTags, selector)
+	}
+
+	for _, account := range config.Accounts {
+		p.accounts[account] = true
+	}
+
+	return p, nil
+}
+
+func newPattern(glob string) pattern {
+	expr := regexp.QuoteMeta(glob)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+
+	return pattern{glob: glob, regexp: regexp.MustCompile("^" + expr + "$")}
+}
+
+func newTagSelector(s string) (tagSelector, error) {
+	kv := strings.SplitN(s, "=", 2)
+	if kv[0] == "" {
+		return tagSelector{}, fmt.Errorf("tag selector must be of form <key>[=<value>]: %s", s)
+	}
+
+	selector := tagSelector{key: kv[0]}
+	if len(kv) == 2 {
+		value := newPattern(kv[1])
+		selector.value = &value
+	}
+
+	return selector, nil
+}
+
+func (s tagSelector) matches(tags map[string]string) bool {
+	value, ok := tags[s.key]
+	if !ok {
+		return false
+	}
+
+	return s.value == nil || s.value.matches(value)
+}
+
+func (p pattern) matches(s string) bool {
+	return p.regexp.MatchString(s)
+}
+
+func (s tagSelector) String() string {
+	if s.value == nil {
+		return s.key
+	}
+	return fmt.Sprintf("%s=%s", s.key, s.value.glob)
+}
+
+// Protects returns true and the reason if the given resource is protected. Tags, names, and account IDs
+// are read from the Terraform state of the resource, so the state must have been fetched before.
+// If accounts are protected, resources of unknown account are protected, too.
+func (p Protection) Protects(r terraform.Resource) (bool, string) {
+	for _, id := range p.ids {
+		if id.matches(r.ID) {
+			return true, fmt.Sprintf("id matches %s", id.glob)
+		}
+	}
+
+	if name, ok := resourceName(r); ok {
+		for _, n := range p.names {
+			if n.matches(name) {
+				return true, fmt.Sprintf("name matches %s", n.glob)
+			}
+		}
+	}
+
+	tags := StateTags(r)
+	for _, selector := range p.tags {
+		if selector.matches(tags) {
+			return true, fmt.Sprintf("tag matches %s", selector)
+		}
+	}
+
+	if len(p.accounts) > 0 {
+		accountID, ok := resourceAccountID(r)
+		if !ok {
+			// fail closed, as the resource might belong to a protected account
+			return true, "account is unknown, but accounts are protected"
+		}
+
+		if p.accounts[accountID] {
+			return true, fmt.Sprintf("account %s is protected", accountID)
+		}
+	}
+
+	return false, ""
+}
+
+// HasProtectionTag returns true and the reason if the fetched state of the given resource has a protection tag,
+// i.e. one of the default protection tags or a protection tag of the protection file.
+func (p Protection) HasProtectionTag(r terraform.Resource) (bool, string) {
+	tags := StateTags(r)
+
+	selectors := p.protectionTags
+	for _, tag := range defaultProtectionTags {
+		selector, _ := newTagSelector(tag)
+		selectors = append(selectors, selector)
+	}
+
+	for _, selector := range selectors {
+		if selector.matches(tags) {
+			return true, fmt.Sprintf("protection tag %s (see --ignore-protection-tag)", selector)
+		}
+	}
+
+	return false, ""
+}
+
+// resourceName returns the name of a resource, which is either its name attribute or its Name tag.
+func resourceName(r terraform.Resource) (string, bool) {
+	v, ok := stateAttribute(r.State, "name")
+	if ok && v.IsKnown() && v.Type() == cty.String {
+		return v.AsString(), true
+	}
+
+	name, ok := StateTags(r)["Name"]
+
+	return name, ok
+}
+
+// resourceAccountID returns the ID of the account a resource belongs to, which is either known from the input
+// (e.g., an ARN) or derived from the ARN or owner ID in the Terraform state of the resource.
+func resourceAccountID(r terraform.Resource) (string, bool) {
+	if r.AccountID != "" {
+		return r.AccountID, true
+	}
+
+	v, ok := stateAttribute(r.State, "arn")
+	if ok && v.IsKnown() && v.Type() == cty.String {
+		a, err := parseARN(v.AsString())
+		if err == nil && a.accountID != "" {
+			return a.accountID, true
+		}
+	}
+
+	v, ok = stateAttribute(r.State, "owner_id")
+	if ok && v.IsKnown() && v.Type() == cty.String && v.AsString() != "" {
+		return v.AsString(), true
+	}
+
+	return "", false
+}
+
+// ApplyProtection splits the given resources into the ones that can be deleted and the ones that are protected.
+func ApplyProtection(resources []terraform.Resource, p Protection) ([]terraform.Resource, []ProtectedResource) {
+	var result []terraform.Resource
+	var protected []ProtectedResource
+
+	for _, r := range resources {
+		if ok, reason := p.Protects(r); ok {
+			protected = append(protected, ProtectedResource{r, reason})
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return result, protected
+}
+
+// ApplyProtectionTags splits the given resources into the ones that can be deleted
+// and the ones that have a protection tag.
+func ApplyProtectionTags(resources []terraform.Resource, p Protection) ([]terraform.Resource, []ProtectedResource) {
+	var result []terraform.Resource
+	var protected []ProtectedResource
+
+	for _, r := range resources {
+		if ok, reason := p.HasProtectionTag(r); ok {
+			protected = append(protected, ProtectedResource{r, reason})
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return result, protected
+}
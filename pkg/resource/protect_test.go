@@ -0,0 +1,174 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestProtection_Protects(t *testing.T) {
+	p, err := ReadProtection(strings.NewReader(`
+ids:
+  - vpc-1
+  - nat-*
+names:
+  - shared-*
+tags:
+  - env=prod*
+  - DoNotDelete
+accounts:
+  - "123456789012"
+`))
+	require.NoError(t, err)
+
+	withState := func(rType, id string, attrs map[string]cty.Value) terraform.Resource {
+		attrs["id"] = cty.StringVal(id)
+		state := cty.ObjectVal(attrs)
+		return terraform.Resource{Type: rType, ID: id, State: &state}
+	}
+
+	tags := func(kv ...string) cty.Value {
+		m := map[string]cty.Value{}
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = cty.StringVal(kv[i+1])
+		}
+		return cty.MapVal(m)
+	}
+
+	tests := []struct {
+		name           string
+		r              terraform.Resource
+		expected       bool
+		expectedReason string
+	}{
+		{
+			name:           "ID",
+			r:              terraform.Resource{Type: "aws_vpc", ID: "vpc-1"},
+			expected:       true,
+			expectedReason: "id matches vpc-1",
+		},
+		{
+			name:           "ID glob",
+			r:              terraform.Resource{Type: "aws_nat_gateway", ID: "nat-0a1b2c"},
+			expected:       true,
+			expectedReason: "id matches nat-*",
+		},
+		{
+			name:     "ID glob is anchored",
+			r:        terraform.Resource{Type: "aws_vpc", ID: "vpc-10", AccountID: "210987654321"},
+			expected: false,
+		},
+		{
+			name:           "name attribute",
+			r:              withState("aws_iam_role", "shared-ci", map[string]cty.Value{"name": cty.StringVal("shared-ci")}),
+			expected:       true,
+			expectedReason: "name matches shared-*",
+		},
+		{
+			name:           "Name tag",
+			r:              withState("aws_instance", "i-1", map[string]cty.Value{"tags": tags("Name", "shared-bastion")}),
+			expected:       true,
+			expectedReason: "name matches shared-*",
+		},
+		{
+			name:           "tag with value",
+			r:              withState("aws_instance", "i-1", map[string]cty.Value{"tags": tags("env", "production")}),
+			expected:       true,
+			expectedReason: "tag matches env=prod*",
+		},
+		{
+			name: "tag with other value",
+			r: withState("aws_instance", "i-1", map[string]cty.Value{
+				"tags":     tags("env", "test"),
+				"owner_id": cty.StringVal("210987654321"),
+			}),
+			expected: false,
+		},
+		{
+			name:           "tag key",
+			r:              withState("aws_instance", "i-1", map[string]cty.Value{"tags": tags("DoNotDelete", "")}),
+			expected:       true,
+			expectedReason: "tag matches DoNotDelete",
+		},
+		{
+			name:           "account from input",
+			r:              terraform.Resource{Type: "aws_instance", ID: "i-1", AccountID: "123456789012"},
+			expected:       true,
+			expectedReason: "account 123456789012 is protected",
+		},
+		{
+			name: "account from ARN in state",
+			r: withState("aws_iam_role", "foo", map[string]cty.Value{
+				"arn": cty.StringVal("arn:aws:iam::123456789012:role/foo"),
+			}),
+			expected:       true,
+			expectedReason: "account 123456789012 is protected",
+		},
+		{
+			name:           "unknown account",
+			r:              withState("aws_instance", "i-1", map[string]cty.Value{}),
+			expected:       true,
+			expectedReason: "account is unknown, but accounts are protected",
+		},
+		{
+			name:     "not protected",
+			r:        withState("aws_instance", "i-1", map[string]cty.Value{"owner_id": cty.StringVal("210987654321")}),
+			expected: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, actualReason := p.Protects(tc.r)
+
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expectedReason, actualReason)
+		})
+	}
+}
+
+func TestReadProtection_UnknownField(t *testing.T) {
+	_, err := ReadProtection(strings.NewReader("id:\n  - vpc-1\n"))
+	assert.Error(t, err)
+}
+
+func TestApplyProtectionTags(t *testing.T) {
+	p, err := ReadProtection(strings.NewReader("protection_tags:\n  - keep=yes\n"))
+	require.NoError(t, err)
+
+	withTags := func(id string, tags map[string]string) terraform.Resource {
+		m := map[string]cty.Value{}
+		for k, v := range tags {
+			m[k] = cty.StringVal(v)
+		}
+
+		state := cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal(id), "tags": cty.MapVal(m)})
+		return terraform.Resource{Type: "aws_instance", ID: id, State: &state}
+	}
+
+	resources := []terraform.Resource{
+		withTags("i-1", map[string]string{"awsrm:protect": "true"}),
+		withTags("i-2", map[string]string{"awsrm:protect": "false"}),
+		withTags("i-3", map[string]string{"DoNotDelete": ""}),
+		withTags("i-4", map[string]string{"keep": "yes"}),
+		withTags("i-5", map[string]string{"Name": "foo"}),
+	}
+
+	actual, actualProtected := ApplyProtectionTags(resources, p)
+
+	assert.Equal(t, []terraform.Resource{resources[1], resources[4]}, actual)
+
+	var actualReasons []string
+	for _, r := range actualProtected {
+		actualReasons = append(actualReasons, r.ID+": "+r.Reason)
+	}
+
+	assert.Equal(t, []string{
+		"i-1: protection tag awsrm:protect=true (see --ignore-protection-tag)",
+		"i-3: protection tag DoNotDelete (see --ignore-protection-tag)",
+		"i-4: protection tag keep=yes (see --ignore-protection-tag)",
+	}, actualReasons)
+}
@@ -2,16 +2,19 @@ package resource
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
+	"sync"
 
 	"github.com/apex/log"
 	"github.com/jckuester/awsrm/internal"
 	"github.com/jckuester/awstools-lib/aws"
 	"github.com/jckuester/awstools-lib/terraform"
 	"github.com/jckuester/awstools-lib/terraform/provider"
-	terradozerRes "github.com/jckuester/terradozer/pkg/resource"
 )
 
 type UpdatedResources struct {
@@ -21,17 +24,67 @@ type UpdatedResources struct {
 
 // Update fetches the Terraform state for the given resources. A state is needed to delete resources
 // via the Delete() function, which calls the Terraform AWS provider for deletion.
+//
+// The state of each resource is fetched separately, so that errors can be attributed to their resources.
 func Update(resources []terraform.Resource, providers map[aws.ClientKey]provider.TerraformProvider) UpdatedResources {
-	withUpdatedState, errs := terraform.UpdateStates(resources, providers, 10, false)
+	type stateFetch struct {
+		updated []terraform.Resource
+		errs    []error
+	}
+
+	fetched := make([]stateFetch, len(resources))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 10)
+
+	for i := range resources {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			updated, errs := terraform.UpdateStates(resources[i:i+1], providers, 1, false)
+			fetched[i] = stateFetch{updated, errs}
+		}(i)
+	}
+
+	wg.Wait()
 
 	var resourcesAlreadyDeleted []terraform.Resource
 	var resourcesToDelete []terraform.Resource
+	var errs []error
+
+	for i, f := range fetched {
+		if len(f.errs) > 0 {
+			for _, err := range f.errs {
+				e := newEvent(EventStateFetchFailed, resources[i])
+				e.Error = err.Error()
+				emitEvent(e)
+			}
+
+			errs = append(errs, f.errs...)
+
+			continue
+		}
+
+		// no state is returned for resources that don't exist
+		if len(f.updated) == 0 {
+			resourcesAlreadyDeleted = append(resourcesAlreadyDeleted, resources[i])
+			EmitEvent(EventAlreadyDeleted, resources[i])
+			continue
+		}
+
+		r := f.updated[0]
 
-	for _, r := range withUpdatedState {
 		if r.State.IsNull() {
 			resourcesAlreadyDeleted = append(resourcesAlreadyDeleted, r)
+			EmitEvent(EventAlreadyDeleted, r)
 		} else {
 			resourcesToDelete = append(resourcesToDelete, r)
+			EmitEvent(EventStateFetched, r)
 		}
 	}
 
@@ -40,114 +93,391 @@ func Update(resources []terraform.Resource, providers map[aws.ClientKey]provider
 	}
 
 	for _, r := range resourcesAlreadyDeleted {
-		log.WithFields(log.Fields{
-			"id":      r.ID,
-			"profile": r.Profile,
-			"region":  r.Region,
-		}).Info(internal.Pad(r.Type))
+		log.WithFields(IdentityFields(r)).Info(internal.Pad(r.Type))
 	}
 
 	return UpdatedResources{resourcesToDelete, errs}
 }
 
+// DeleteOptions configures how resources are deleted.
+type DeleteOptions struct {
+	Force  bool
+	DryRun bool
+	// Selection is how the user selects the resources to delete.
+	Selection Selection
+	// Recursive shows the resources that would be deleted as a tree, where each resource is listed below a resource
+	// it depends on (e.g., the subnets below their VPC).
+	Recursive bool
+	// MaxRetries is the number of times resources that failed to be deleted for a retryable reason
+	// (e.g., DependencyViolation) are retried.
+	MaxRetries int
+	// Reason is why the resources are deleted (e.g., a ticket reference), which is shown with the resources to delete.
+	Reason string
+	// BeforeDestroy, if not nil, is called for each resource just before it is deleted (e.g., to tag it with
+	// the reason). If it returns an error, the resource isn't deleted and fails with that error.
+	BeforeDestroy func(terraform.Resource) error
+	// BeforeDeletion, if not nil, is called with the confirmed resources before any of them is deleted
+	// (e.g., to record the plan). If it returns an error, nothing is deleted.
+	BeforeDeletion func(confirmed []terraform.Resource) error
+	// Interrupted, once closed, stops deleting: deletions in progress are finished, but no further resources
+	// are deleted (they fail with ErrInterrupted).
+	Interrupted <-chan struct{}
+	// DisplayAttributes are shown with the resources (e.g., the attributes printed by awsls, as returned by Read).
+	DisplayAttributes DisplayAttributes
+}
+
+// DeleteStatus is the outcome of Delete.
+type DeleteStatus int
+
+const (
+	// DeleteCompleted means all resources have been deleted (or would have been, in a dry run).
+	DeleteCompleted DeleteStatus = iota
+	// DeleteNothingToDo means no resources were found or selected to delete.
+	DeleteNothingToDo
+	// DeleteAborted means the user didn't confirm the deletion or quit the selection of resources.
+	DeleteAborted
+	// DeleteFailed means some resources couldn't be deleted.
+	DeleteFailed
+)
+
+// DeleteResult is the result of Delete.
+type DeleteResult struct {
+	Status DeleteStatus
+	// Confirmed are the resources that the user confirmed to delete.
+	Confirmed []terraform.Resource
+	// Deleted are the resources that have been deleted.
+	Deleted []terraform.Resource
+	// Failed are the resources that couldn't be deleted.
+	Failed []FailedResource
+}
+
 // Delete deletes the given resources via the Terraform AWS Provider.
-func Delete(resources []terraform.Resource, confirmDevice io.Reader, force bool, dryRun bool, done chan bool) {
+// Protected resources are only listed as skipped, and never deleted.
+//
+// An error is returned if the user's selection of resources fails (e.g., the editor can't be started).
+func Delete(resources []terraform.Resource, protected []ProtectedResource, confirmDevice io.Reader,
+	opts DeleteOptions) (DeleteResult, error) {
+	if len(protected) != 0 {
+		internal.LogTitle("protected, skipped")
+	}
+	for _, r := range protected {
+		fields := logFields(r.Resource, opts.DisplayAttributes)
+		fields["reason"] = r.Reason
+
+		log.WithFields(fields).Info(internal.Pad(r.Type))
+
+		e := newEvent(EventProtected, r.Resource)
+		e.Reason = r.Reason
+		emitEvent(e)
+	}
+
 	if len(resources) == 0 {
 		internal.LogTitle("no resources found to delete")
-		done <- true
-		return
+		return DeleteResult{Status: DeleteNothingToDo}, nil
 	}
 
 	// always show the resources that would be affected before deleting anything
 	if len(resources) != 0 {
 		internal.LogTitle("showing resources that would be deleted (dry run)")
 	}
-	for _, r := range resources {
-		if r.State != nil {
-			log.WithFields(log.Fields{
-				"id":      r.ID,
-				"profile": r.Profile,
-				"region":  r.Region,
-			}).Warn(internal.Pad(r.Type))
+	if opts.Recursive {
+		for _, n := range newDependencyGraph(resources).tree() {
+			log.WithFields(logFields(n.resource, opts.DisplayAttributes)).Warn(internal.Pad(formatTreeNode(n)))
+		}
+	} else {
+		for _, r := range resources {
+			if r.State != nil {
+				log.WithFields(logFields(r, opts.DisplayAttributes)).Warn(internal.Pad(r.Type))
+			}
 		}
 	}
 
 	internal.LogTitle(fmt.Sprintf("total number of resources that would be deleted: %d", len(resources)))
 
-	if !dryRun && len(resources) > 0 {
-		if !force {
-			if !internal.UserConfirmedDeletion(confirmDevice) {
-				done <- true
-				return
-			}
+	switch opts.Selection {
+	case SelectEach:
+		internal.LogTitle("select resources to delete")
+
+		resources = selectEach(resources, opts.DisplayAttributes, confirmDevice)
+	case SelectChecklist, SelectEditor:
+		var err error
+
+		if opts.Selection == SelectChecklist {
+			resources, err = selectChecklist(resources, opts.DisplayAttributes, confirmDevice)
 		} else {
-			internal.LogTitle("Proceeding with deletion and skipping confirmation (Force)")
+			resources, err = selectInEditor(resources, opts.DisplayAttributes, confirmDevice)
+		}
+		if errors.Is(err, internal.ErrChecklistAborted) {
+			internal.LogTitle("selection aborted")
+			emitEvent(Event{Event: EventAborted})
+			return DeleteResult{Status: DeleteAborted}, nil
+		}
+		if err != nil {
+			return DeleteResult{}, err
+		}
+
+		if len(resources) != 0 {
+			internal.LogTitle("selected resources")
+		}
+		for _, r := range resources {
+			log.WithFields(logFields(r, opts.DisplayAttributes)).Warn(internal.Pad(r.Type))
+		}
+	}
+
+	if opts.Selection != SelectAll {
+		if len(resources) == 0 {
+			internal.LogTitle("no resources selected to delete")
+			return DeleteResult{Status: DeleteNothingToDo}, nil
 		}
 
-		internal.LogTitle("Starting to delete resources")
+		internal.LogTitle(fmt.Sprintf("total number of selected resources: %d", len(resources)))
+	}
+
+	for _, r := range resources {
+		EmitEvent(EventPlanned, r)
+	}
+
+	graph := newDependencyGraph(resources)
+
+	_, cycles := graph.deletionLayers()
+
+	if len(cycles) != 0 {
+		internal.LogTitle("found dependency cycles (the resources of each cycle are deleted together)")
+	}
+	for _, cycle := range cycles {
+		log.Warn(formatCycle(cycle))
+	}
+
+	if opts.Reason != "" {
+		internal.LogTitle("reason for deletion")
+		log.Info(opts.Reason)
+	}
+
+	if opts.DryRun {
+		return DeleteResult{Status: DeleteCompleted}, nil
+	}
+
+	switch {
+	case opts.Selection == SelectEach:
+		// the user has already confirmed each resource
+	case opts.Force:
+		internal.LogTitle("Proceeding with deletion and skipping confirmation (Force)")
+	default:
+		if !internal.UserConfirmedDeletion(confirmDevice) {
+			emitEvent(Event{Event: EventAborted})
+			return DeleteResult{Status: DeleteAborted}, nil
+		}
+	}
 
-		numDeletedResources := terradozerRes.DestroyResources(convertToDestroyable(resources), 5)
+	for _, r := range resources {
+		e := newEvent(EventConfirmed, r)
+		e.Reason = opts.Reason
+		emitEvent(e)
+	}
 
-		internal.LogTitle(fmt.Sprintf("total number of deleted resources: %d", numDeletedResources))
+	if opts.BeforeDeletion != nil {
+		err := opts.BeforeDeletion(resources)
+		if err != nil {
+			return DeleteResult{}, err
+		}
 	}
 
-	done <- true
+	internal.LogTitle("Starting to delete resources")
+
+	deleted, failed := destroyResources(resources, opts)
+
+	internal.LogTitle(fmt.Sprintf("total number of deleted resources: %d", len(deleted)))
+
+	if len(failed) != 0 {
+		internal.LogTitle(fmt.Sprintf("failed to delete the following resources: %d", len(failed)))
+	}
+	for _, f := range failed {
+		fields := IdentityFields(f.Resource)
+		fields["error"] = formatError(f.Err)
+
+		log.WithFields(fields).Warn(internal.Pad(f.Type))
+	}
+
+	result := DeleteResult{Status: DeleteCompleted, Confirmed: resources, Deleted: deleted, Failed: failed}
+	if len(failed) != 0 {
+		result.Status = DeleteFailed
+	}
+
+	return result, nil
+}
+
+// logFields returns the fields to log for a resource, including any attributes (e.g., tags) given as input.
+func logFields(r terraform.Resource, displayAttrs DisplayAttributes) log.Fields {
+	fields := IdentityFields(r)
+
+	if r.CreatedAt != nil {
+		fields["created"] = r.CreatedAt.Format(awslsTimeFormat)
+	}
+
+	if len(r.Tags) > 0 {
+		fields["tags"] = formatTags(r.Tags)
+	}
+
+	for name, value := range r.Attrs {
+		if _, ok := fields[name]; ok {
+			continue
+		}
+
+		fields[name] = formatAttribute(value)
+	}
+
+	for name, value := range displayAttrs.of(r) {
+		if _, ok := fields[name]; ok {
+			continue
+		}
+
+		fields[name] = value
+	}
+
+	return fields
 }
 
 // Read reads resources from stdIn (when input is coming from pipe), where a line must be of the following format:
 // 	<resource_type> <resource_id> <profile> <region>\n
-func Read(r io.Reader) ([]terraform.Resource, error) {
+//
+// or an ARN, from which the resource type, ID, region, and account ID are derived. Lines starting with # are ignored.
+//
+// Alternatively, the input can be JSON, either a top-level array or one object per line, where each object
+// has the fields "type", "id", and optionally "profile", "region", "account_id", and "attributes".
+//
+// All lines of input are read, even if some are invalid. In that case, the resources of all valid lines are returned
+// together with an error of type LineErrors, which describes each invalid line.
+//
+// The attributes printed by awsls (via the -a flag) that are only shown are returned separately.
+func Read(r io.Reader) ([]terraform.Resource, DisplayAttributes, error) {
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isJSON(input) {
+		resources, err := readJSON(input)
+		return resources, nil, err
+	}
+
 	var result []terraform.Resource
+	var header *awslsHeader
+	var lineErrs LineErrors
 
-	scanner := bufio.NewScanner(bufio.NewReader(r))
+	displayAttrs := DisplayAttributes{}
+
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(input))
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
+
+		// ignore empty lines and comments
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		// the header line of awsls beginning with "TYPE ID..." is used to locate the columns of the following lines
+		if isAwslsHeader(line) {
+			header, err = parseAwslsHeader(line)
+			if err != nil {
+				log.WithError(err).Debug("ignoring header line")
+			}
 
-		// ignore empty lines and header lines of awsls beginning with "TYPE ID..."
-		if line == "\n" || line == "" || strings.HasPrefix(line, "TYPE") {
 			continue
 		}
 
-		rAttrs := strings.Fields(line)
-		if len(rAttrs) < 4 {
-			return nil, fmt.Errorf("input must be of form: <resource_type> <resource_id> <profile> <region>")
+		r, attrs, err := readLine(line, header)
+		if err != nil {
+			lineErrs = append(lineErrs, LineError{Line: lineNumber, Text: line, Err: err})
+			continue
 		}
 
-		rType := PrefixResourceType(rAttrs[0])
-		if !terraform.IsType(rType) {
-			return nil, fmt.Errorf("no resource type found: %s\n", rType)
+		if attrs != nil {
+			displayAttrs[resourceKey(r)] = attrs
 		}
 
-		profile := rAttrs[2]
+		result = append(result, r)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(lineErrs) > 0 {
+		return result, displayAttrs, lineErrs
+	}
+
+	return result, displayAttrs, nil
+}
 
-		if profile == `N/A` {
-			profile = ""
+// readLine reads a resource from a line of input. If a header printed by awsls is given,
+// the values of its columns are read, including any attributes following the region column,
+// of which the ones that are only shown are returned.
+func readLine(line string, header *awslsHeader) (terraform.Resource, map[string]string, error) {
+	rAttrs := strings.Fields(line)
+
+	if IsARN(rAttrs[0]) {
+		r, err := FromARN(rAttrs[0])
+		if err == nil && r.AccountID == "" {
+			log.Warnf("ARN without account ID, deleting with the default credentials: %s", rAttrs[0])
 		}
 
-		result = append(result, terraform.Resource{
-			Type:    rType,
-			ID:      rAttrs[1],
-			Profile: profile,
-			Region:  rAttrs[3],
-		})
+		return r, nil, err
 	}
 
-	err := scanner.Err()
+	values, aligned := splitLine(line, header)
+
+	r, err := resourceFromValues(values)
 	if err != nil {
-		return nil, err
+		return terraform.Resource{}, nil, err
 	}
 
-	return result, nil
+	if !aligned {
+		return r, nil, nil
+	}
+
+	return r, header.setAttributes(&r, values), nil
 }
 
-func convertToDestroyable(resources []terraform.Resource) []terradozerRes.DestroyableResource {
-	var result []terradozerRes.DestroyableResource
+// splitLine returns the values of the columns of a line of input. If a header printed by awsls is given
+// and the line is aligned with it, the values are located by the columns of the header (so that values
+// can contain spaces) and true is returned; otherwise, the line is split at whitespace.
+func splitLine(line string, header *awslsHeader) ([]string, bool) {
+	if header != nil {
+		values, aligned := header.split(line)
+		if aligned {
+			return values, true
+		}
+	}
 
-	for _, r := range resources {
-		result = append(result, terradozerRes.Resource{
-			Resource: r,
-		})
+	return strings.Fields(line), false
+}
+
+// resourceFromValues returns the resource of the values of the type, ID, profile, and region column of a line.
+func resourceFromValues(values []string) (terraform.Resource, error) {
+	if len(values) < 4 || values[0] == "" || values[1] == "" {
+		return terraform.Resource{},
+			fmt.Errorf("line must be of form: <resource_type> <resource_id> <profile> <region>")
+	}
+
+	rType := PrefixResourceType(values[0])
+	if !terraform.IsType(rType) {
+		return terraform.Resource{}, fmt.Errorf("no resource type found: %s", rType)
+	}
+
+	profile := values[2]
+
+	if profile == `N/A` {
+		profile = ""
 	}
 
-	return result
+	return terraform.Resource{
+		Type:    rType,
+		ID:      values[1],
+		Profile: profile,
+		Region:  values[3],
+	}, nil
 }
@@ -0,0 +1,112 @@
+package resource
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRead_InvalidLines(t *testing.T) {
+	input := `aws_vpc vpc-1 myaccount us-east-1
+aws_vpc vpc-2
+
+aws_foo foo-1 myaccount us-east-1
+aws_vpc vpc-3 N/A us-east-1
+`
+
+	actualResources, _, err := Read(strings.NewReader(input))
+	require.Error(t, err)
+
+	assert.Equal(t, []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+		{Type: "aws_vpc", ID: "vpc-3", Region: "us-east-1"},
+	}, actualResources)
+
+	var lineErrs LineErrors
+	require.True(t, errors.As(err, &lineErrs))
+	require.Len(t, lineErrs, 2)
+
+	assert.Equal(t, 2, lineErrs[0].Line)
+	assert.Equal(t, "aws_vpc vpc-2", lineErrs[0].Text)
+	assert.EqualError(t, lineErrs[0].Err, "line must be of form: <resource_type> <resource_id> <profile> <region>")
+
+	assert.Equal(t, 4, lineErrs[1].Line)
+	assert.Equal(t, "aws_foo foo-1 myaccount us-east-1", lineErrs[1].Text)
+	assert.EqualError(t, lineErrs[1].Err, "no resource type found: aws_foo")
+}
+
+func TestDelete_Status(t *testing.T) {
+	vpc := withState("aws_vpc", "vpc-1", map[string]cty.Value{})
+	accessDenied := errors.New("UnauthorizedOperation: You are not authorized to perform this operation")
+
+	var testCases = []struct {
+		name            string
+		resources       []terraform.Resource
+		opts            DeleteOptions
+		confirm         string
+		failures        map[string][]error
+		expectedStatus  DeleteStatus
+		expectedDeleted int
+		expectedFailed  int
+	}{
+		{
+			name:           "no resources",
+			expectedStatus: DeleteNothingToDo,
+		},
+		{
+			name:           "dry run",
+			resources:      []terraform.Resource{vpc},
+			opts:           DeleteOptions{DryRun: true},
+			expectedStatus: DeleteCompleted,
+		},
+		{
+			name:           "not confirmed",
+			resources:      []terraform.Resource{vpc},
+			confirm:        "no\n",
+			expectedStatus: DeleteAborted,
+		},
+		{
+			name:           "none selected",
+			resources:      []terraform.Resource{vpc},
+			opts:           DeleteOptions{Selection: SelectEach},
+			confirm:        "q\n",
+			expectedStatus: DeleteNothingToDo,
+		},
+		{
+			name:            "deleted",
+			resources:       []terraform.Resource{vpc},
+			confirm:         "yes\n",
+			expectedStatus:  DeleteCompleted,
+			expectedDeleted: 1,
+		},
+		{
+			name:           "failed",
+			resources:      []terraform.Resource{vpc},
+			opts:           DeleteOptions{Force: true},
+			failures:       map[string][]error{"vpc-1": {accessDenied}},
+			expectedStatus: DeleteFailed,
+			expectedFailed: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeDestroy{failures: tc.failures}
+
+			destroy = fake.destroy
+			defer func() { destroy = defaultDestroy }()
+
+			result, err := Delete(tc.resources, nil, strings.NewReader(tc.confirm), tc.opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, result.Status)
+			assert.Len(t, result.Failed, tc.expectedFailed)
+			assert.Len(t, result.Deleted, tc.expectedDeleted)
+		})
+	}
+}
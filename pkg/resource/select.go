@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// Selection is how the user selects the resources to delete.
+type Selection int
+
+const (
+	// SelectAll asks the user once to confirm the deletion of all resources.
+	SelectAll Selection = iota
+	// SelectEach asks the user for each resource whether to delete it (like rm -i).
+	SelectEach
+	// SelectChecklist lets the user toggle resources in a full-screen checklist.
+	SelectChecklist
+	// SelectEditor lets the user remove resources from a list in an editor (like git rebase -i).
+	SelectEditor
+)
+
+// selectEach asks the user for each resource whether to delete it and returns the selected resources.
+func selectEach(resources []terraform.Resource, displayAttrs DisplayAttributes, r io.Reader) []terraform.Resource {
+	var result []terraform.Resource
+
+	for i, res := range resources {
+		log.WithFields(logFields(res, displayAttrs)).Warn(internal.Pad(res.Type))
+
+		switch internal.UserAnswer(r) {
+		case internal.AnswerYes:
+			result = append(result, res)
+		case internal.AnswerAll:
+			return append(result, resources[i:]...)
+		case internal.AnswerQuit:
+			return result
+		}
+	}
+
+	return result
+}
+
+// selectChecklist shows the resources grouped by profile, region, and type in a full-screen checklist
+// on the terminal and returns the selected resources, or internal.ErrChecklistAborted if the user quits.
+func selectChecklist(resources []terraform.Resource, displayAttrs DisplayAttributes,
+	r io.Reader) ([]terraform.Resource, error) {
+	tty, ok := r.(*os.File)
+	if !ok {
+		return nil, fmt.Errorf("checklist requires a terminal")
+	}
+
+	sorted := make([]terraform.Resource, len(resources))
+	copy(sorted, resources)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return checklistGroup(sorted[i]) < checklistGroup(sorted[j])
+	})
+
+	var items []internal.ChecklistItem
+	for _, r := range sorted {
+		items = append(items, internal.ChecklistItem{
+			Group:    checklistGroup(r),
+			Label:    checklistLabel(r, displayAttrs),
+			Selected: true,
+		})
+	}
+
+	selection, err := internal.TerminalChecklist(tty, "SELECT RESOURCES TO DELETE", items)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []terraform.Resource
+	for i, item := range selection {
+		if item.Selected {
+			result = append(result, sorted[i])
+		}
+	}
+
+	return result, nil
+}
+
+func checklistGroup(r terraform.Resource) string {
+	profile := r.Profile
+	if profile == "" {
+		profile = "N/A"
+	}
+
+	if r.AccountID != "" {
+		profile += " (account " + formatAccount(r.AccountID) + ")"
+	}
+
+	return fmt.Sprintf("%s / %s / %s", profile, r.Region, r.Type)
+}
+
+// checklistLabel returns the ID of a resource followed by its attributes given as input (e.g., tags).
+func checklistLabel(r terraform.Resource, displayAttrs DisplayAttributes) string {
+	fields := logFields(r, displayAttrs)
+	for name := range IdentityFields(r) {
+		delete(fields, name)
+	}
+
+	var attrs []string
+	for name, value := range fields {
+		attrs = append(attrs, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(attrs)
+
+	return strings.TrimSpace(r.ID + "   " + strings.Join(attrs, " "))
+}
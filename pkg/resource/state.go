@@ -0,0 +1,112 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// stateV4 is the subset of a Terraform state file (format version 4) needed to find the resources it manages.
+type stateV4 struct {
+	Version   int               `json:"version"`
+	Resources []stateV4Resource `json:"resources"`
+}
+
+type stateV4Resource struct {
+	Mode      string                    `json:"mode"`
+	Type      string                    `json:"type"`
+	Name      string                    `json:"name"`
+	Instances []stateV4ResourceInstance `json:"instances"`
+}
+
+type stateV4ResourceInstance struct {
+	Attributes struct {
+		ID     string `json:"id"`
+		ARN    string `json:"arn"`
+		Region string `json:"region"`
+	} `json:"attributes"`
+}
+
+// IgnoredStateResource is a managed resource of a Terraform state file that can't be deleted.
+type IgnoredStateResource struct {
+	Type string
+	// Name is the name of the resource in the Terraform configuration.
+	Name string
+	// Reason is why the resource can't be deleted.
+	Reason string
+}
+
+// ReadState reads all managed AWS resources from a Terraform state file (format version 4) and returns them
+// together with the managed resources that can't be deleted (e.g., of an unsupported type or without ID).
+//
+// Data sources are ignored, as they are not managed by the state. A state file doesn't store the
+// profile and region of the provider that manages a resource, therefore the region is taken from
+// the resource's ARN or region attribute (if present), otherwise it is left empty.
+func ReadState(r io.Reader) ([]terraform.Resource, []IgnoredStateResource, error) {
+	var state stateV4
+
+	err := json.NewDecoder(r).Decode(&state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Terraform state: %s", err)
+	}
+
+	if state.Version != 4 {
+		return nil, nil, fmt.Errorf("unsupported Terraform state version: %d (only version 4 is supported)",
+			state.Version)
+	}
+
+	var result []terraform.Resource
+	var ignored []IgnoredStateResource
+
+	for _, rs := range state.Resources {
+		if rs.Mode != "managed" {
+			continue
+		}
+
+		if !strings.HasPrefix(rs.Type, "aws_") {
+			ignored = append(ignored, IgnoredStateResource{Type: rs.Type, Name: rs.Name,
+				Reason: "not an AWS resource"})
+			continue
+		}
+
+		if !terraform.IsType(rs.Type) {
+			ignored = append(ignored, IgnoredStateResource{Type: rs.Type, Name: rs.Name,
+				Reason: "unsupported resource type"})
+			continue
+		}
+
+		for _, instance := range rs.Instances {
+			if instance.Attributes.ID == "" {
+				ignored = append(ignored, IgnoredStateResource{Type: rs.Type, Name: rs.Name,
+					Reason: "no ID in state"})
+				continue
+			}
+
+			region := instance.Attributes.Region
+			if region == "" {
+				region = regionFromARN(instance.Attributes.ARN)
+			}
+
+			result = append(result, terraform.Resource{
+				Type:   rs.Type,
+				ID:     instance.Attributes.ID,
+				Region: region,
+			})
+		}
+	}
+
+	return result, ignored, nil
+}
+
+// regionFromARN returns the region part of an ARN, which is empty for global resources.
+func regionFromARN(s string) string {
+	a, err := parseARN(s)
+	if err != nil {
+		return ""
+	}
+
+	return a.region
+}
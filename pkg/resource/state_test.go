@@ -0,0 +1,98 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testState = `{
+  "version": 4,
+  "terraform_version": "0.14.7",
+  "serial": 3,
+  "lineage": "c3a3a1b4-2e5e-4b6b-8f7f-0a5f3c1c3b1e",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "data",
+      "type": "aws_caller_identity",
+      "name": "current",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"schema_version": 0, "attributes": {"id": "123456789012"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_vpc",
+      "name": "test",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {
+          "schema_version": 1,
+          "attributes": {"id": "vpc-1", "arn": "arn:aws:ec2:us-west-2:123456789012:vpc/vpc-1"}
+        },
+        {
+          "index_key": 1,
+          "schema_version": 1,
+          "attributes": {"id": "vpc-2", "arn": "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-2"}
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_iam_role",
+      "name": "test",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {"schema_version": 0, "attributes": {"id": "foo", "arn": "arn:aws:iam::123456789012:role/foo"}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_foo",
+      "name": "unsupported",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"schema_version": 0, "attributes": {"id": "foo-1"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_subnet",
+      "name": "tainted",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"schema_version": 1, "attributes": {"id": ""}}]
+    },
+    {
+      "mode": "managed",
+      "type": "random_id",
+      "name": "test",
+      "provider": "provider[\"registry.terraform.io/hashicorp/random\"]",
+      "instances": [{"schema_version": 0, "attributes": {"id": "abc"}}]
+    }
+  ]
+}`
+
+func TestReadState(t *testing.T) {
+	actualResources, actualIgnored, err := ReadState(strings.NewReader(testState))
+	require.NoError(t, err)
+
+	assert.Equal(t, []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Region: "us-west-2"},
+		{Type: "aws_vpc", ID: "vpc-2", Region: "us-east-1"},
+		{Type: "aws_iam_role", ID: "foo"},
+	}, actualResources)
+
+	assert.Equal(t, []IgnoredStateResource{
+		{Type: "aws_foo", Name: "unsupported", Reason: "unsupported resource type"},
+		{Type: "aws_subnet", Name: "tainted", Reason: "no ID in state"},
+		{Type: "random_id", Name: "test", Reason: "not an AWS resource"},
+	}, actualIgnored)
+}
+
+func TestReadState_UnsupportedVersion(t *testing.T) {
+	_, _, err := ReadState(strings.NewReader(`{"version": 3, "modules": []}`))
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "unsupported Terraform state version: 3")
+}
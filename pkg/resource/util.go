@@ -1,6 +1,15 @@
 package resource
 
-import "strings"
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
 
 // PrefixResourceType prefixes a resource type
 // with "aws_" to be a valid Terraform resource type for the AWS provider.
@@ -10,3 +19,108 @@ func PrefixResourceType(rType string) string {
 	}
 	return rType
 }
+
+// RemoveDuplicates removes resources of the same type and ID in the same profile and region.
+func RemoveDuplicates(resources []terraform.Resource) []terraform.Resource {
+	type key struct {
+		rType, id, profile, region string
+	}
+
+	seen := map[key]bool{}
+
+	var result []terraform.Resource
+
+	for _, r := range resources {
+		k := key{r.Type, r.ID, r.Profile, r.Region}
+
+		if seen[k] {
+			continue
+		}
+
+		seen[k] = true
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// globalTypePrefixes are the prefixes of resource types that are global, i.e. not bound to a region.
+var globalTypePrefixes = []string{
+	"aws_iam_",
+	"aws_route53_",
+	"aws_cloudfront_",
+	"aws_organizations_",
+	"aws_globalaccelerator_",
+	"aws_waf_",
+}
+
+// IsGlobalType returns true if resources of the given type are global, i.e. they exist in all regions of an account
+// (e.g., IAM roles or Route 53 hosted zones).
+func IsGlobalType(rType string) bool {
+	// unlike other Route 53 resources, the ones of the Route 53 Resolver are regional
+	if strings.HasPrefix(rType, "aws_route53_resolver_") {
+		return false
+	}
+
+	for _, prefix := range globalTypePrefixes {
+		if strings.HasPrefix(rType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceKey identifies a resource by its type and ID in a profile and region.
+func resourceKey(r terraform.Resource) string {
+	return strings.Join([]string{r.Type, r.ID, r.Profile, r.Region}, " ")
+}
+
+// formatTags formats tags the same way as awsls prints them, i.e. <key>=<value>,<key>=<value>.
+func formatTags(tags map[string]string) string {
+	var list []string
+
+	for k, v := range tags {
+		list = append(list, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	sort.Strings(list)
+
+	return strings.Join(list, ",")
+}
+
+// formatAttribute returns a human-readable representation of an attribute value.
+func formatAttribute(v cty.Value) string {
+	if v.IsNull() || !v.IsWhollyKnown() {
+		return `N/A`
+	}
+
+	switch v.Type() {
+	case cty.String:
+		return v.AsString()
+	case cty.Number:
+		return v.AsBigFloat().Text('f', -1)
+	case cty.Bool:
+		return fmt.Sprint(v.True())
+	}
+
+	result, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return v.GoString()
+	}
+
+	return string(result)
+}
+
+// trimTrailingSpaces removes the spaces at the end of each line of the given text,
+// such as the padding of a tabwriter at the end of lines with an empty last column.
+func trimTrailingSpaces(s string) string {
+	var result strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		result.WriteString(strings.TrimRight(scanner.Text(), " ") + "\n")
+	}
+
+	return result.String()
+}
@@ -1,6 +1,11 @@
 package resource
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jckuester/awstools-lib/terraform"
+)
 
 func TestResourceTypePrefixed(t *testing.T) {
 	type args struct {
@@ -34,3 +39,39 @@ func TestResourceTypePrefixed(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveDuplicates(t *testing.T) {
+	resources := []terraform.Resource{
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-west-2"},
+		{Type: "aws_vpc", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+		{Type: "aws_subnet", ID: "vpc-1", Profile: "myaccount", Region: "us-east-1"},
+	}
+
+	want := []terraform.Resource{resources[0], resources[1], resources[3]}
+
+	if got := RemoveDuplicates(resources); !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveDuplicates() = %v, want %v", got, want)
+	}
+}
+
+func TestIsGlobalType(t *testing.T) {
+	tests := []struct {
+		rType string
+		want  bool
+	}{
+		{"aws_iam_role", true},
+		{"aws_route53_zone", true},
+		{"aws_cloudfront_distribution", true},
+		{"aws_route53_resolver_endpoint", false},
+		{"aws_instance", false},
+		{"aws_wafv2_web_acl", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rType, func(t *testing.T) {
+			if got := IsGlobalType(tt.rType); got != tt.want {
+				t.Errorf("IsGlobalType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/pkg/resource"
+)
+
+// defaultProtectFile returns the path to the protection file that is used if none is given via --protect-file.
+func defaultProtectFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "protect.yaml"), nil
+}
+
+// loadProtection reads the protection rules from the given file or, if no file is given,
+// from the default protection file if it exists.
+func loadProtection(path string) (resource.Protection, error) {
+	if path == "" {
+		defaultPath, err := defaultProtectFile()
+		if err != nil {
+			return resource.Protection{}, err
+		}
+
+		_, err = os.Stat(defaultPath)
+		if os.IsNotExist(err) {
+			return resource.Protection{}, nil
+		}
+
+		path = defaultPath
+	}
+
+	log.WithField("path", path).Debug("reading protection file")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return resource.Protection{}, err
+	}
+	defer f.Close()
+
+	p, err := resource.ReadProtection(f)
+	if err != nil {
+		return resource.Protection{}, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return p, nil
+}
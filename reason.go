@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// deletedReasonTagKey is the key of the tag with the reason for deletion, which is applied to each resource
+// just before it is deleted, so that the reason shows up in CloudTrail's tagging events.
+const deletedReasonTagKey = "awsrm:deleted-reason"
+
+// maxTagValueLength is the maximum number of characters of tag values of AWS resources.
+const maxTagValueLength = 256
+
+// ec2TaggableTypes are the types of resources whose ID can be tagged via EC2's CreateTags.
+var ec2TaggableTypes = map[string]bool{
+	"aws_instance":                     true,
+	"aws_vpc":                          true,
+	"aws_subnet":                       true,
+	"aws_security_group":               true,
+	"aws_internet_gateway":             true,
+	"aws_egress_only_internet_gateway": true,
+	"aws_nat_gateway":                  true,
+	"aws_route_table":                  true,
+	"aws_network_acl":                  true,
+	"aws_network_interface":            true,
+	"aws_vpc_endpoint":                 true,
+	"aws_vpc_peering_connection":       true,
+	"aws_eip":                          true,
+	"aws_ebs_volume":                   true,
+	"aws_ebs_snapshot":                 true,
+	"aws_ami":                          true,
+	"aws_launch_template":              true,
+	"aws_customer_gateway":             true,
+	"aws_vpn_gateway":                  true,
+	"aws_ec2_transit_gateway":          true,
+}
+
+// reasonTagger tags resources with the reason for deletion. Each resource is tagged only once, even if
+// deleting it is retried; a resource for which tagging failed is tagged again on the next attempt.
+//
+// If tagging is required, a resource of a supported type that fails to be tagged isn't deleted; otherwise,
+// it is deleted anyway. Resources of unsupported types are always deleted untagged.
+type reasonTagger struct {
+	ctx      context.Context
+	reason   string
+	required bool
+
+	mu      sync.Mutex
+	clients map[aws.ClientKey]*aws.Client
+	tagged  map[string]bool
+}
+
+func newReasonTagger(ctx context.Context, reason string, required bool) *reasonTagger {
+	if chars := []rune(reason); len(chars) > maxTagValueLength {
+		reason = string(chars[:maxTagValueLength])
+	}
+
+	return &reasonTagger{
+		ctx:      ctx,
+		reason:   reason,
+		required: required,
+		clients:  map[aws.ClientKey]*aws.Client{},
+		tagged:   map[string]bool{},
+	}
+}
+
+// tag tags the resource with the reason for deletion, unless it has been tagged before. An error is only returned
+// if tagging is required and failed, in which case the resource must not be deleted.
+func (t *reasonTagger) tag(r terraform.Resource) error {
+	key := fmt.Sprintf("%s %s %s %s", r.Type, r.ID, r.Profile, r.Region)
+
+	t.mu.Lock()
+	alreadyTagged := t.tagged[key]
+	t.mu.Unlock()
+
+	if alreadyTagged {
+		return nil
+	}
+
+	if !ec2TaggableTypes[r.Type] && r.Type != "aws_iam_role" && r.Type != "aws_iam_user" {
+		log.WithFields(resource.IdentityFields(r)).Debugf("tagging resources of type %s is not supported", r.Type)
+		return nil
+	}
+
+	client, err := t.client(r.Profile, r.Region)
+	if err == nil {
+		err = tagResource(t.ctx, client, r, t.reason)
+	}
+
+	if err != nil {
+		if t.required {
+			return fmt.Errorf("failed to tag resource with %s (required by tag_reason): %s", deletedReasonTagKey, err)
+		}
+
+		log.WithFields(resource.IdentityFields(r)).WithError(err).Warnf("failed to tag resource with %s",
+			deletedReasonTagKey)
+
+		return nil
+	}
+
+	t.mu.Lock()
+	t.tagged[key] = true
+	t.mu.Unlock()
+
+	return nil
+}
+
+func (t *reasonTagger) client(profile, region string) (*aws.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := aws.ClientKey{Profile: profile, Region: region}
+
+	if client, ok := t.clients[k]; ok {
+		return client, nil
+	}
+
+	client, err := newClient(t.ctx, profile, region)
+	if err != nil {
+		return nil, err
+	}
+
+	t.clients[k] = client
+
+	return client, nil
+}
+
+// tagResource tags a resource of a type supported by reasonTagger with the reason for deletion.
+func tagResource(ctx context.Context, client *aws.Client, r terraform.Resource, reason string) error {
+	key := deletedReasonTagKey
+
+	switch r.Type {
+	case "aws_iam_role":
+		_, err := client.Iamconn.TagRole(ctx, &iam.TagRoleInput{
+			RoleName: &r.ID,
+			Tags:     []iamtypes.Tag{{Key: &key, Value: &reason}},
+		})
+		return err
+	case "aws_iam_user":
+		_, err := client.Iamconn.TagUser(ctx, &iam.TagUserInput{
+			UserName: &r.ID,
+			Tags:     []iamtypes.Tag{{Key: &key, Value: &reason}},
+		})
+		return err
+	}
+
+	_, err := client.Ec2conn.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{r.ID},
+		Tags:      []ec2types.Tag{{Key: &key, Value: &reason}},
+	})
+
+	return err
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/apex/log"
+	awsls "github.com/jckuester/awsls/aws"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awsrm/pkg/resource"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+	"github.com/jckuester/awstools-lib/terraform/provider"
+)
+
+// referringTypes are, for each resource type, the types of resources that can reference a resource of that type
+// (e.g., instances reference their security groups via vpc_security_group_ids).
+var referringTypes = map[string][]string{
+	"aws_vpc": {
+		"aws_subnet", "aws_route_table", "aws_security_group", "aws_internet_gateway", "aws_nat_gateway",
+		"aws_vpc_endpoint", "aws_network_interface", "aws_instance", "aws_lb", "aws_lb_target_group",
+		"aws_eks_cluster", "aws_lambda_function",
+	},
+	"aws_subnet": {
+		"aws_instance", "aws_network_interface", "aws_nat_gateway", "aws_lb", "aws_db_subnet_group",
+		"aws_lambda_function", "aws_eks_cluster", "aws_autoscaling_group",
+	},
+	"aws_security_group": {
+		"aws_instance", "aws_network_interface", "aws_security_group", "aws_lb", "aws_db_instance",
+		"aws_lambda_function", "aws_launch_template", "aws_launch_configuration", "aws_eks_cluster",
+		"aws_vpc_endpoint", "aws_elasticache_replication_group",
+	},
+	"aws_internet_gateway":  {"aws_route_table"},
+	"aws_nat_gateway":       {"aws_route_table"},
+	"aws_network_interface": {"aws_route_table"},
+	"aws_iam_role": {
+		"aws_lambda_function", "aws_iam_instance_profile", "aws_ecs_task_definition", "aws_sfn_state_machine",
+		"aws_codebuild_project", "aws_eks_cluster", "aws_batch_compute_environment",
+	},
+	"aws_iam_policy":           {"aws_iam_role"},
+	"aws_iam_instance_profile": {"aws_instance", "aws_launch_template", "aws_launch_configuration"},
+	"aws_launch_template":      {"aws_autoscaling_group"},
+	"aws_launch_configuration": {"aws_autoscaling_group"},
+	"aws_lb_target_group":      {"aws_autoscaling_group"},
+	"aws_db_subnet_group":      {"aws_db_instance"},
+	"aws_lambda_function":      {"aws_lambda_event_source_mapping"},
+	"aws_ecs_cluster":          {"aws_ecs_task_definition"},
+	"aws_efs_file_system":      {"aws_efs_access_point"},
+}
+
+// listReferrers lists the resources in the accounts and regions of the given resources that can reference them
+// (see referringTypes) and fetches their state. Only resources in the same account and region as the given ones
+// are returned. Resources of a type that can't be listed (e.g., due to missing permissions) are skipped
+// with a warning.
+func listReferrers(ctx context.Context, resources []terraform.Resource,
+	providers map[aws.ClientKey]provider.TerraformProvider) ([]terraform.Resource, error) {
+	typesByClientKey := map[aws.ClientKey]map[string]bool{}
+	accountByClientKey := map[aws.ClientKey]string{}
+
+	for _, r := range resources {
+		k := aws.ClientKey{Profile: r.Profile, Region: r.Region}
+
+		if typesByClientKey[k] == nil {
+			typesByClientKey[k] = map[string]bool{}
+		}
+
+		accountByClientKey[k] = r.AccountID
+
+		for _, t := range referringTypes[r.Type] {
+			typesByClientKey[k][t] = true
+		}
+
+		if _, ok := referringTypes[r.Type]; !ok {
+			log.WithFields(resource.IdentityFields(r)).Debugf("finding dependents of type %s is not supported", r.Type)
+		}
+	}
+
+	var result []terraform.Resource
+
+	for k, rTypes := range typesByClientKey {
+		if len(rTypes) == 0 {
+			continue
+		}
+
+		client, err := newClient(ctx, k.Profile, k.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		// some listers only return resources owned by the account
+		err = client.SetAccountID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var sortedTypes []string
+		for t := range rTypes {
+			sortedTypes = append(sortedTypes, t)
+		}
+		sort.Strings(sortedTypes)
+
+		for _, t := range sortedTypes {
+			// listing can take a while, so it is stopped as soon as awsrm is interrupted
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			listed, err := awsls.ListResourcesByType(ctx, client, t)
+			if err != nil {
+				log.WithError(err).WithFields(log.Fields{
+					"profile": k.Profile,
+					"region":  k.Region,
+				}).Warnf("failed to list resources of type %s", t)
+
+				continue
+			}
+
+			numListed := 0
+
+			for _, r := range listed {
+				if r.Region != k.Region || (accountByClientKey[k] != "" && r.AccountID != accountByClientKey[k]) {
+					log.WithFields(resource.IdentityFields(r)).Debug("ignoring resource in other account or region")
+					continue
+				}
+
+				result = append(result, r)
+				numListed++
+			}
+
+			log.WithFields(log.Fields{
+				"profile": k.Profile,
+				"region":  k.Region,
+			}).Infof("listed %d resource(s) of type %s", numListed, t)
+		}
+	}
+
+	if len(result) != 0 {
+		internal.LogTitle(fmt.Sprintf("fetching the state of %d resource(s) that might depend on others", len(result)))
+	}
+
+	withState, errs := terraform.UpdateStates(result, providers, 10, false)
+	for _, err := range errs {
+		log.WithError(err).Debug("failed to fetch state of resource")
+	}
+
+	var existing []terraform.Resource
+	for _, r := range withState {
+		if r.State != nil && !r.State.IsNull() {
+			existing = append(existing, r)
+		}
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/apex/log"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/jckuester/awstools-lib/aws"
+)
+
+// expandToAllRegions returns a client key for each region that is enabled in the account of each given client
+// (i.e., the profile of each client is combined with all its enabled regions).
+func expandToAllRegions(ctx context.Context, clients map[aws.ClientKey]aws.Client) ([]aws.ClientKey, error) {
+	var result []aws.ClientKey
+
+	seenProfiles := map[string]bool{}
+
+	for _, client := range clients {
+		if seenProfiles[client.Profile] {
+			continue
+		}
+		seenProfiles[client.Profile] = true
+
+		resp, err := client.Ec2conn.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe enabled regions (profile=%s): %s", client.Profile, err)
+		}
+
+		var regions []string
+		for _, r := range resp.Regions {
+			regions = append(regions, *r.RegionName)
+		}
+
+		sort.Strings(regions)
+
+		log.WithFields(log.Fields{
+			"profile": client.Profile,
+			"regions": regions,
+		}).Debug("found enabled regions")
+
+		for _, region := range regions {
+			result = append(result, aws.ClientKey{Profile: client.Profile, Region: region})
+		}
+	}
+
+	return result, nil
+}
+
+// singleValue returns the only value of a flag that can be given at most once, or an empty string if not given.
+func singleValue(flagName string, values []string) (string, error) {
+	switch len(values) {
+	case 0:
+		return "", nil
+	case 1:
+		return values[0], nil
+	default:
+		return "", fmt.Errorf("only one value allowed for flag --%s in this mode", flagName)
+	}
+}
+
+// newClient returns an AWS client for the given profile and region. If the profile or region is empty,
+// it is picked up via the usual default provider chain.
+func newClient(ctx context.Context, profile, region string) (*aws.Client, error) {
+	var profiles []string
+	if profile != "" {
+		profiles = []string{profile}
+	}
+
+	var regions []string
+	if region != "" {
+		regions = []string{region}
+	}
+
+	clients, err := aws.NewClientPool(ctx, profiles, regions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, client := range clients {
+		return &client, nil
+	}
+
+	return nil, fmt.Errorf("no AWS client created")
+}
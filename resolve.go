@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/jckuester/awsrm/internal"
+	"github.com/jckuester/awstools-lib/aws"
+	"github.com/jckuester/awstools-lib/terraform"
+)
+
+// setProfilesByAccountID sets the profile of resources that have an account ID (e.g., derived from an ARN),
+// but no profile, by matching the account ID against the profiles in the AWS config file.
+//
+// An error is returned if no profile is found for any of the accounts, as the default credentials
+// might belong to another account.
+func setProfilesByAccountID(resources []terraform.Resource) error {
+	var profiles map[string]string
+	var missing []string
+
+	for i := range resources {
+		r := &resources[i]
+
+		if r.Profile != "" || r.AccountID == "" {
+			continue
+		}
+
+		if profiles == nil {
+			profiles = map[string]string{}
+
+			path, err := internal.AWSConfigFile()
+			if err == nil {
+				profiles, err = internal.ProfilesByAccountID(path)
+			}
+			if err != nil {
+				log.WithError(err).Debug("failed to read profiles from AWS config file")
+			}
+		}
+
+		profile, ok := profiles[r.AccountID]
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%s %s (account %s)", r.Type, r.ID, r.AccountID))
+			continue
+		}
+
+		r.Profile = profile
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("no profile found in AWS config for the account of: %s "+
+			"(add a profile for the account or give a profile explicitly)", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// setDefaultRegions sets the region of resources without region (e.g., global resources)
+// to the default region of their profile.
+func setDefaultRegions(ctx context.Context, resources []terraform.Resource) error {
+	defaultRegions := map[string]string{}
+
+	for i := range resources {
+		r := &resources[i]
+
+		if r.Region != "" {
+			continue
+		}
+
+		region, ok := defaultRegions[r.Profile]
+		if !ok {
+			var profiles []string
+			if r.Profile != "" {
+				profiles = []string{r.Profile}
+			}
+
+			clients, err := aws.NewClientPool(ctx, profiles, nil)
+			if err != nil {
+				return err
+			}
+
+			for k := range clients {
+				region = k.Region
+			}
+
+			defaultRegions[r.Profile] = region
+		}
+
+		r.Region = region
+	}
+
+	return nil
+}
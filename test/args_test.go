@@ -349,7 +349,7 @@ func TestAcc_Args_UnsupportedResourceType(t *testing.T) {
 
 	actualLogs := logBuffer.String()
 
-	assert.Contains(t, actualLogs, "Error: no resource type found: aws_unsupported")
+	assert.Contains(t, actualLogs, "Error: argument aws_unsupported: no resource type found: aws_unsupported")
 
 	fmt.Println(actualLogs)
 }
@@ -184,7 +184,7 @@ func TestAcc_Pipe_UnsupportedResourceType(t *testing.T) {
 
 	actualLogs := logBuffer.String()
 
-	assert.Contains(t, actualLogs, "Error: no resource type found: aws_unsupported")
+	assert.Contains(t, actualLogs, "Error: stdin:1: no resource type found: aws_unsupported: ")
 
 	fmt.Println(actualLogs)
 }